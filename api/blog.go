@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 // this file defines the Restful api of Blog
 // type BlogParams struct {
 // 	// BlogId is the id of the blog
@@ -13,8 +15,10 @@ type BlogResponse struct {
 	Title string `json:"title"`
 	// Content is the content of the blog
 	Summary string `json:"summary"`
-	// Date is the date of the blog
-	Date string `json:"date"`
+	// Date is the publication date of the blog, serialized as RFC 3339 with offset
+	Date time.Time `json:"date"`
+	// UpdatedAt is the last modification time of the blog, omitted if unknown
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
 }
 
 type BlogContent struct {
@@ -22,6 +26,8 @@ type BlogContent struct {
 	ID int `json:"id"`
 	//Text is the content of the blog
 	Text string `json:"text"`
+	// ModTime is the mtime of the markdown file, used for Last-Modified only
+	ModTime time.Time `json:"-"`
 }
 
 type Error struct {
@@ -0,0 +1,20 @@
+package api
+
+// ImageVariant is one stored rendition of an uploaded image
+type ImageVariant struct {
+	// URL is the public /static path of the file
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Bytes is the file size
+	Bytes int `json:"bytes"`
+}
+
+type UploadResponse struct {
+	// Original is the uploaded image with location metadata removed
+	Original ImageVariant `json:"original"`
+	// Variants holds resized renditions keyed by name (thumbnail, medium)
+	Variants map[string]ImageVariant `json:"variants"`
+	// Markdown is a ready-to-paste image reference to the original
+	Markdown string `json:"markdown"`
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/archive"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// runExport 处理 export 子命令：导出博客文档、markdown 与静态资源
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("o", "", "output archive path (default blog-backup-<time>.tar.gz)")
+	static := fs.String("static-dir", os.Getenv("STATIC_DIR"), "static assets directory, empty to skip")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *out == "" {
+		*out = fmt.Sprintf("blog-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	mongo := utils.NewMongoManager(utils.MongoConfigFromEnv(), nil)
+	defer mongo.Close(context.Background())
+	blogs, err := mongo.Blogs(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+
+	// markdown 只从 CONTENT_ROOT 内读取；根目录不可用时只导出文档与静态资源
+	root, err := utils.ContentRootFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: warning: %s, markdown will not be archived\n", err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+	m, err := archive.Export(ctx, blogs, f, archive.ExportOptions{StaticDir: *static, ContentRoot: root})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*out)
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+	for _, p := range m.Posts {
+		if p.ContentError != "" {
+			fmt.Fprintf(os.Stderr, "export: warning: blog %d markdown %q not archived: %s\n", p.ID, p.OriginalPath, p.ContentError)
+		}
+	}
+	fmt.Printf("exported %d post(s), %d file(s) to %s\n", len(m.Posts), len(m.Files), *out)
+	return 0
+}
+
+// runImportArchive 处理 import-archive 子命令：校验并恢复 export 生成的归档
+func runImportArchive(args []string) int {
+	fs := flag.NewFlagSet("import-archive", flag.ContinueOnError)
+	static := fs.String("static-dir", os.Getenv("STATIC_DIR"), "restore static assets into this directory, empty to skip")
+	content := fs.String("content-dir", "", "restore markdown into this directory inside CONTENT_ROOT and rewrite Path (default: original paths)")
+	allowOutside := fs.Bool("allow-outside-root", false, "allow restoring markdown outside CONTENT_ROOT (trusted archives only)")
+	dryRun := fs.Bool("dry-run", false, "verify the archive without writing anything")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "import-archive: archive path required")
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	mongo := utils.NewMongoManager(utils.MongoConfigFromEnv(), nil)
+	defer mongo.Close(context.Background())
+	blogs, err := mongo.Blogs(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import-archive: %s\n", err)
+		return 1
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import-archive: %s\n", err)
+		return 1
+	}
+	defer f.Close()
+	// markdown 限定写入 CONTENT_ROOT 内；根目录不可用时由 Import 拒绝写入
+	root, err := utils.ContentRootFromEnv()
+	if err != nil && !*allowOutside {
+		fmt.Fprintf(os.Stderr, "import-archive: warning: %s\n", err)
+	}
+	res, err := archive.Import(ctx, blogs, f, archive.ImportOptions{
+		StaticDir:        *static,
+		ContentDir:       *content,
+		ContentRoot:      root,
+		AllowOutsideRoot: *allowOutside,
+		DryRun:           *dryRun,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import-archive: %s\n", err)
+		return 1
+	}
+	fmt.Printf("restored %d post(s), %d file(s) written, %d unchanged\n", res.Posts, res.FilesWritten, res.FilesSkipped)
+	return 0
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// runCommand 执行维护子命令，返回进程退出码
+func runCommand(name string, args []string) int {
+	switch name {
+	case "migrate":
+		return runMigrate(args)
+	case "export":
+		return runExport(args)
+	case "import-archive":
+		return runImportArchive(args)
+	case "migrate-to-bolt":
+		return runMigrateToBolt(args)
+	case "audit-paths":
+		return runAuditPaths(args)
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", name)
+		printUsage()
+		return 2
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  my-blog-server                  start HTTP server
+  my-blog-server migrate [up|down|status] [flags]
+  my-blog-server export [-o file] [-static-dir dir]
+  my-blog-server import-archive [-content-dir dir] [-static-dir dir] [-dry-run] file
+  my-blog-server migrate-to-bolt [-bolt-path file]
+  my-blog-server audit-paths
+
+migrate, export and import-archive operate on MongoDB.`)
+}
@@ -1,14 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	// 内嵌时区数据，BLOG_TIMEZONE 在缺少 zoneinfo 的机器上也能解析
+	_ "time/tzdata"
 
 	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+	"github.com/LtePrince/Personal-Website-backend/internal/tracing"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
 )
 
 func main() {
+	// 子命令（migrate 等）：执行完即退出；无参数时启动 HTTP 服务
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
 	// 环境变量读取
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -20,13 +34,76 @@ func main() {
 		staticDir = "/www/wwwroot/Personal-Blog-db/static"
 	}
 
+	// 链路追踪（OTEL_TRACES_EXPORTER 未设置时为 no-op）
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		fmt.Printf("Error initializing tracing: %s\n", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	// 内容根目录：markdown 只允许从此目录读取
+	// 目录不可用时不阻塞启动，但博客正文一律返回不存在
+	contentRoot, err := utils.ContentRootFromEnv()
+	if err != nil {
+		fmt.Printf("Warning: %s, blog content disabled\n", err)
+	}
+
+	// 博客存储：STORAGE_BACKEND=mongo（默认）或 bolt
+	store, err := openStore(contentRoot)
+	if err != nil {
+		fmt.Printf("Error opening blog store: %s\n", err)
+		os.Exit(1)
+	}
+	defer store.Close(context.Background())
+	if mongo, ok := store.(*utils.MongoManager); ok && os.Getenv("MONGO_AUTO_MIGRATE") != "false" {
+		// 启动时自动执行迁移，可设置 MONGO_AUTO_MIGRATE=false 关闭
+		autoMigrate(mongo)
+	}
+
+	staticCacheControl := os.Getenv("STATIC_CACHE_CONTROL")
+	if staticCacheControl == "" {
+		staticCacheControl = "public, max-age=3600"
+	}
+
 	// 静态资源服务，访问 /static/xxx.jpg 实际读取 static 目录下的文件
 	// http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("/home/adolph/workspace/Personal-website/blogs/static"))))
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(staticDir))))
+	// 带 w/q/fmt 参数的图片请求按需缩放，/img/ 为等价的独立路由
+	resizer := handlers.NewImageResizer(handlers.ImageResizeConfigFromEnv(staticDir, staticCacheControl))
+	http.Handle("/static/", handlers.Instrument("/static/", http.StripPrefix("/static/", resizer.Middleware(handlers.NewStaticHandler(staticDir, staticCacheControl)))))
+	http.Handle("/img/", handlers.Instrument("/img/", http.StripPrefix("/img/", resizer)))
+	// 图片上传（需设置 ADMIN_TOKEN），写入 STATIC_DIR
+	http.Handle("/api/Upload", handlers.Instrument("/api/Upload", handlers.NewUploader(handlers.UploadConfigFromEnv(staticDir))))
+	// Prometheus 指标
+	http.Handle("/metrics", metrics.Handler())
+
+	// 博客正文与列表元数据 LRU 缓存
+	cache := utils.NewContentCache(store, contentRoot, utils.ContentCacheConfigFromEnv())
+	metrics.RegisterCache("content", cache.Stats)
+	metrics.RegisterCache("ipgeo", utils.DefaultIPGeoCache().Stats)
+	metrics.RegisterCache("weather", utils.DefaultWeatherCache().Stats)
+
+	server := handlers.NewServer(cache, handlers.CachePolicyFromEnv())
+	http.HandleFunc("/", server.Handler)
+	// 压缩作用于 JSON 接口与 /static/ 下的文本资源（按 Content-Type 允许列表）
+	mux := handlers.Compress(handlers.CompressConfigFromEnv(), http.DefaultServeMux)
+	srv := &http.Server{Addr: ":" + port, Handler: tracing.Middleware(mux)}
+
+	// 收到退出信号时优雅关闭，确保 defer 中的 trace 刷新得以执行
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	// 内容热加载：轮询 CONTENT_ROOT 下的 markdown，变更后更新存储与缓存
+	startContentWatcher(ctx, contentRoot, store, cache)
 
-	http.HandleFunc("/", handlers.Handler)
 	fmt.Printf("Server is listening on port %s (static: %s) ...\n", port, staticDir)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		fmt.Printf("Error starting server: %s\n", err)
 	}
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/migrations"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// runMigrate 处理 migrate 子命令：up（默认）/ down / status
+func runMigrate(args []string) int {
+	action := "up"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	to := fs.Int("to", 0, "up: migrate up to this version (0 = latest)")
+	steps := fs.Int("steps", 1, "down: number of migrations to revert")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	mongo := utils.NewMongoManager(utils.MongoConfigFromEnv(), nil)
+	defer mongo.Close(context.Background())
+	blogs, err := mongo.Blogs(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+		return 1
+	}
+	runner := migrations.NewRunner(blogs)
+
+	switch action {
+	case "up":
+		n, err := runner.Up(ctx, *to)
+		fmt.Printf("applied %d migration(s)\n", n)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+			return 1
+		}
+	case "down":
+		n, err := runner.Down(ctx, *steps)
+		fmt.Printf("reverted %d migration(s)\n", n)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+			return 1
+		}
+	case "status":
+		list, err := runner.Status(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+			return 1
+		}
+		for _, st := range list {
+			applied := "pending"
+			if st.Applied {
+				applied = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-24s %s\n", st.Version, st.Name, applied)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "migrate: unknown action %q\n", action)
+		return 2
+	}
+	return 0
+}
+
+// autoMigrate 启动时执行未应用的迁移；失败只记录日志，不阻塞服务启动
+func autoMigrate(mongo *utils.MongoManager) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	blogs, err := mongo.Blogs(ctx)
+	if err != nil {
+		fmt.Printf("Warning: skip migrations: %s\n", err)
+		return
+	}
+	if n, err := migrations.NewRunner(blogs).Up(ctx, 0); err != nil {
+		fmt.Printf("Warning: migrations failed after %d applied: %s\n", n, err)
+	} else if n > 0 {
+		fmt.Printf("Applied %d migration(s)\n", n)
+	}
+}
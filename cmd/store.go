@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// boltPath 返回 bbolt 数据文件路径（BOLT_PATH，默认 data/blog.db）
+func boltPath() string {
+	if p := os.Getenv("BOLT_PATH"); p != "" {
+		return p
+	}
+	return "data/blog.db"
+}
+
+// openStore 按 STORAGE_BACKEND 打开博客存储：mongo（默认）或 bolt，
+// markdown 只允许从 root 内读取
+func openStore(root *utils.ContentRoot) (utils.BlogStore, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "mongo":
+		// MongoDB 连接管理：预先建连，失败不阻塞启动，由后续请求按退避重试
+		mongo := utils.NewMongoManager(utils.MongoConfigFromEnv(), root)
+		if err := mongo.Connect(context.Background()); err != nil {
+			fmt.Printf("Warning: %s\n", err)
+		}
+		return mongo, nil
+	case "bolt":
+		return utils.OpenBoltStore(boltPath(), root)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+// runMigrateToBolt 处理 migrate-to-bolt 子命令：将 Mongo 博客集合一次性复制到 bbolt 文件
+func runMigrateToBolt(args []string) int {
+	fs := flag.NewFlagSet("migrate-to-bolt", flag.ContinueOnError)
+	path := fs.String("bolt-path", boltPath(), "target bolt database file (existing blogs are replaced)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	mongo := utils.NewMongoManager(utils.MongoConfigFromEnv(), nil)
+	defer mongo.Close(context.Background())
+	bolt, err := utils.OpenBoltStore(*path, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate-to-bolt: %s\n", err)
+		return 1
+	}
+	defer bolt.Close(context.Background())
+
+	n, err := utils.CopyToBolt(ctx, mongo, bolt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate-to-bolt: %s\n", err)
+		return 1
+	}
+	fmt.Printf("copied %d blog(s) to %s\n", n, *path)
+	return 0
+}
+
+// runAuditPaths 处理 audit-paths 子命令：列出 Path 越出内容根目录或文件缺失的文档
+func runAuditPaths(args []string) int {
+	fs := flag.NewFlagSet("audit-paths", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	root, err := utils.ContentRootFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit-paths: %s\n", err)
+		return 1
+	}
+	store, err := openStore(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit-paths: %s\n", err)
+		return 1
+	}
+	defer store.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	violations, err := utils.AuditContentPaths(ctx, store, root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit-paths: %s\n", err)
+		return 1
+	}
+	fmt.Printf("content root: %s\n", root.Dir())
+	for _, v := range violations {
+		fmt.Printf("ID=%-5d %-22s %q (%s)\n", v.ID, v.Reason, v.Path, v.Title)
+	}
+	fmt.Printf("%d offending document(s)\n", len(violations))
+	if len(violations) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// startContentWatcher 按 CONTENT_WATCH_INTERVAL（默认 10s，0 关闭）在后台启动内容监视
+func startContentWatcher(ctx context.Context, root *utils.ContentRoot, store utils.BlogStore, cache *utils.ContentCache) {
+	interval := 10 * time.Second
+	if v := os.Getenv("CONTENT_WATCH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			fmt.Printf("Warning: invalid CONTENT_WATCH_INTERVAL %q, content watcher disabled\n", v)
+			return
+		}
+		interval = d
+	}
+	if interval <= 0 || root == nil {
+		return
+	}
+	writer, ok := store.(utils.BlogWriter)
+	if !ok {
+		fmt.Println("Warning: blog store is read-only, content watcher disabled")
+		return
+	}
+	go utils.NewContentWatcher(root, store, writer, cache, interval).Run(ctx)
+}
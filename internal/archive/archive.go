@@ -0,0 +1,606 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 归档格式（tar.gz）：
+//
+//	posts.jsonl          每行一个博客文档（Canonical Extended JSON，保留 BSON 类型）
+//	content/<ID>/<name>  文档 Path 指向的 markdown 文件
+//	static/<rel>         STATIC_DIR 下的资源
+//	manifest.json        版本、计数及所有条目的大小与 SHA-256（最后写入）
+const (
+	FormatName    = "personal-website-backup"
+	FormatVersion = 1
+
+	manifestName = "manifest.json"
+	postsName    = "posts.jsonl"
+	contentDir   = "content/"
+	staticDir    = "static/"
+)
+
+// Manifest 归档清单
+type Manifest struct {
+	Format    string      `json:"format"`
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Posts     []PostEntry `json:"posts"`
+	Files     []FileEntry `json:"files"`
+}
+
+// PostEntry 记录博客与其 markdown 在归档中的对应关系
+type PostEntry struct {
+	ID           int    `json:"id"`
+	OriginalPath string `json:"originalPath,omitempty"`
+	Content      string `json:"content,omitempty"` // 归档内路径，文件缺失时为空
+	// ContentError 有 Path 但未能归档 markdown 的原因（文件缺失或越出内容根目录）
+	ContentError string `json:"contentError,omitempty"`
+}
+
+// FileEntry 归档中的一个文件及其校验和
+type FileEntry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Collection 导出与导入用到的集合操作，*mongo.Collection 实现了该接口
+type Collection interface {
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error)
+}
+
+// ExportOptions 导出选项
+type ExportOptions struct {
+	StaticDir string // 为空时不导出静态资源
+	// ContentRoot 文档 Path 按此根目录解析，只归档根目录内的 markdown；为 nil 时不导出 markdown
+	ContentRoot *utils.ContentRoot
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	StaticDir string // 静态资源写入目录，为空时跳过静态资源
+	// ContentDir 非空时 markdown 写入此目录，文档 Path 改写为相对 ContentRoot 的路径；
+	// 为空时写回原路径
+	ContentDir string
+	// ContentRoot 写回原路径时，原路径必须位于此根目录内（清单中的路径不可信）
+	ContentRoot *utils.ContentRoot
+	// AllowOutsideRoot 允许写回根目录之外的原路径（包括绝对路径），或 ContentDir 位于根目录之外
+	// （此时 Path 记为绝对路径），仅用于可信归档
+	AllowOutsideRoot bool
+	DryRun           bool // 仅校验归档，不写数据库与文件
+}
+
+// ImportResult 导入统计
+type ImportResult struct {
+	Posts        int
+	FilesWritten int
+	FilesSkipped int // 目标文件内容一致，无需写入
+}
+
+// Export 将博客集合、markdown 与静态资源写入 w（tar.gz）
+func Export(ctx context.Context, blogs Collection, w io.Writer, opts ExportOptions) (*Manifest, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	m := &Manifest{Format: FormatName, Version: FormatVersion, CreatedAt: time.Now().UTC()}
+
+	cursor, err := blogs.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "ID", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blogs: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var lines []byte
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID   int    `bson:"ID"`
+			Path string `bson:"Path"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode blog: %v", err)
+		}
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode blog %d: %v", doc.ID, err)
+		}
+		lines = append(append(lines, line...), '\n')
+
+		entry := PostEntry{ID: doc.ID, OriginalPath: doc.Path}
+		if doc.Path != "" {
+			// 与读取博客一致：Path 相对内容根目录解析，越界的文件不会被读入归档
+			real, err := resolveContent(opts.ContentRoot, doc.Path)
+			if err == nil {
+				name := fmt.Sprintf("%s%d/%s", contentDir, doc.ID, filepath.Base(real))
+				var fe FileEntry
+				if fe, err = addFile(tw, name, real); err == nil {
+					entry.Content = name
+					m.Files = append(m.Files, fe)
+				}
+			}
+			switch {
+			case err == nil:
+			case errors.Is(err, fs.ErrNotExist), errors.Is(err, utils.ErrPathEscapesRoot), errors.Is(err, errNoContentRoot):
+				// 仍导出文档，并在清单中记录未归档的原因
+				entry.ContentError = err.Error()
+			default:
+				return nil, err
+			}
+		}
+		m.Posts = append(m.Posts, entry)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %v", err)
+	}
+
+	fe, err := addBytes(tw, postsName, lines)
+	if err != nil {
+		return nil, err
+	}
+	m.Files = append(m.Files, fe)
+
+	if opts.StaticDir != "" {
+		err := filepath.WalkDir(opts.StaticDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(opts.StaticDir, p)
+			if err != nil {
+				return err
+			}
+			fe, err := addFile(tw, staticDir+filepath.ToSlash(rel), p)
+			if err != nil {
+				return err
+			}
+			m.Files = append(m.Files, fe)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive static dir: %v", err)
+		}
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := addBytes(tw, manifestName, manifest); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// errNoContentRoot 未提供内容根目录，无法安全地读取 markdown
+var errNoContentRoot = errors.New("no content root configured")
+
+// resolveContent 将文档 Path 解析为内容根目录内的文件
+func resolveContent(root *utils.ContentRoot, p string) (string, error) {
+	if root == nil {
+		return "", errNoContentRoot
+	}
+	return root.Resolve(p)
+}
+
+// addFile 将磁盘文件写入归档并返回校验信息
+func addFile(tw *tar.Writer, name, src string) (FileEntry, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return FileEntry{}, err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: st.Size(), ModTime: st.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return FileEntry{}, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tw, h), f)
+	if err != nil {
+		return FileEntry{}, fmt.Errorf("failed to archive %s: %v", src, err)
+	}
+	return FileEntry{Name: name, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// addBytes 将内存数据写入归档
+func addBytes(tw *tar.Writer, name string, b []byte) (FileEntry, error) {
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(b)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return FileEntry{}, err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return FileEntry{}, err
+	}
+	sum := sha256.Sum256(b)
+	return FileEntry{Name: name, Size: int64(len(b)), SHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+// Import 校验并恢复归档。文档按 ID upsert，文件内容一致时跳过写入，
+// 因此对空库或已有数据的库重复执行结果相同。所有写入位置校验通过后才开始写入
+func Import(ctx context.Context, blogs Collection, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	tmp, err := os.MkdirTemp("", "blog-import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	sums, err := extract(r, tmp)
+	if err != nil {
+		return nil, err
+	}
+	m, err := readManifest(filepath.Join(tmp, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	// 校验：清单内每个条目必须存在且校验和一致，且不允许清单外的条目
+	for _, fe := range m.Files {
+		got, ok := sums[fe.Name]
+		if !ok {
+			return nil, fmt.Errorf("archive missing %s", fe.Name)
+		}
+		if got != fe.SHA256 {
+			return nil, fmt.Errorf("checksum mismatch for %s", fe.Name)
+		}
+		delete(sums, fe.Name)
+	}
+	delete(sums, manifestName)
+	if len(sums) > 0 {
+		extra := make([]string, 0, len(sums))
+		for name := range sums {
+			extra = append(extra, name)
+		}
+		sort.Strings(extra)
+		return nil, fmt.Errorf("unexpected archive entries: %s", strings.Join(extra, ", "))
+	}
+
+	res := &ImportResult{}
+	docs, err := readPosts(filepath.Join(tmp, postsName))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]PostEntry, len(m.Posts))
+	for _, p := range m.Posts {
+		byID[p.ID] = p
+	}
+
+	archived := make(map[string]bool, len(m.Files))
+	for _, fe := range m.Files {
+		archived[fe.Name] = true
+	}
+
+	// 先确定每篇博客的写入位置，任何一个不合法都不做任何写入
+	type restore struct {
+		id       int
+		doc      bson.D
+		src, dst string
+	}
+	plan := make([]restore, 0, len(docs))
+	usedNames := make(map[string]bool)
+	for _, doc := range docs {
+		id, ok := docID(doc)
+		if !ok {
+			return nil, errors.New("archived blog without numeric ID")
+		}
+		item := restore{id: id, doc: doc}
+		entry := byID[id]
+		if entry.Content != "" {
+			if !strings.HasPrefix(entry.Content, contentDir) || !archived[entry.Content] {
+				return nil, fmt.Errorf("blog %d: content %q is not an archived file", id, entry.Content)
+			}
+			dst, docPath, err := contentDest(entry, id, opts, usedNames)
+			if err != nil {
+				return nil, err
+			}
+			if docPath != "" {
+				item.doc = setField(doc, "Path", docPath)
+			}
+			item.src = filepath.Join(tmp, filepath.FromSlash(entry.Content))
+			item.dst = dst
+		}
+		plan = append(plan, item)
+	}
+
+	for _, item := range plan {
+		if item.dst != "" {
+			written, err := restoreFile(item.src, item.dst, opts.DryRun)
+			if err != nil {
+				return nil, err
+			}
+			countFile(res, written)
+		}
+		if !opts.DryRun {
+			if err := upsertPost(ctx, blogs, item.id, item.doc); err != nil {
+				return nil, err
+			}
+		}
+		res.Posts++
+	}
+
+	if opts.StaticDir != "" {
+		for _, fe := range m.Files {
+			if !strings.HasPrefix(fe.Name, staticDir) {
+				continue
+			}
+			rel := strings.TrimPrefix(fe.Name, staticDir)
+			written, err := restoreFile(filepath.Join(tmp, filepath.FromSlash(fe.Name)), filepath.Join(opts.StaticDir, filepath.FromSlash(rel)), opts.DryRun)
+			if err != nil {
+				return nil, err
+			}
+			countFile(res, written)
+		}
+	}
+	return res, nil
+}
+
+// contentDest 计算 markdown 的写入位置；docPath 非空时需改写文档的 Path。
+// 写回原路径时，原路径必须位于 ContentRoot 内，除非显式设置 AllowOutsideRoot
+func contentDest(entry PostEntry, id int, opts ImportOptions, usedNames map[string]bool) (dst, docPath string, err error) {
+	if opts.ContentDir != "" {
+		// 平铺到 ContentDir；同名文件以 ID 前缀区分
+		name := filepath.Base(entry.OriginalPath)
+		if name == "." || name == ".." || name == string(filepath.Separator) {
+			name = fmt.Sprintf("%d.md", id)
+		}
+		if usedNames[name] {
+			name = fmt.Sprintf("%d_%s", id, name)
+		}
+		usedNames[name] = true
+		dst, err = filepath.Abs(filepath.Join(opts.ContentDir, name))
+		if err != nil {
+			return "", "", err
+		}
+		// Path 记为相对内容根目录的路径，与读取时的解析方式一致，不依赖进程工作目录
+		if opts.ContentRoot != nil {
+			if confined, err := opts.ContentRoot.Confine(dst); err == nil {
+				rel, err := opts.ContentRoot.Rel(confined)
+				return confined, rel, err
+			}
+		}
+		if opts.AllowOutsideRoot {
+			return dst, dst, nil
+		}
+		return "", "", fmt.Errorf("blog %d: content dir %q is not inside the content root", id, opts.ContentDir)
+	}
+	if strings.TrimSpace(entry.OriginalPath) == "" {
+		return "", "", fmt.Errorf("blog %d: archived content has no original path", id)
+	}
+	if opts.AllowOutsideRoot && filepath.IsAbs(entry.OriginalPath) {
+		return filepath.Clean(entry.OriginalPath), "", nil
+	}
+	// 相对路径一律相对内容根目录，不依赖进程工作目录
+	if opts.ContentRoot == nil {
+		return "", "", fmt.Errorf("blog %d: a content root is required to restore markdown to its original path", id)
+	}
+	dst, err = opts.ContentRoot.Confine(entry.OriginalPath)
+	if err != nil {
+		return "", "", fmt.Errorf("blog %d: refusing to restore markdown to %q: %w", id, entry.OriginalPath, err)
+	}
+	return dst, "", nil
+}
+
+func countFile(res *ImportResult, written bool) {
+	if written {
+		res.FilesWritten++
+	} else {
+		res.FilesSkipped++
+	}
+}
+
+// extract 解包到 dir 并计算每个条目的 SHA-256；拒绝绝对路径与 .. 等越界条目
+func extract(r io.Reader, dir string) (map[string]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a gzip archive: %v", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	sums := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if name != hdr.Name || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid archive entry %q", hdr.Name)
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return nil, err
+		}
+		f, err := os.Create(dst)
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		_, err = io.Copy(io.MultiWriter(f, h), tr)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %v", name, err)
+		}
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums, nil
+}
+
+func readManifest(p string) (*Manifest, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("archive has no manifest: %v", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if m.Format != FormatName {
+		return nil, fmt.Errorf("unknown archive format %q", m.Format)
+	}
+	if m.Version < 1 || m.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", m.Version)
+	}
+	return &m, nil
+}
+
+func readPosts(p string) ([]bson.D, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("archive has no posts: %v", err)
+	}
+	var docs []bson.D
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON([]byte(line), true, &doc); err != nil {
+			return nil, fmt.Errorf("invalid archived blog: %v", err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// docID 读取文档中的数字 ID（int32/int64/double 均可）
+func docID(doc bson.D) (int, bool) {
+	for _, e := range doc {
+		if e.Key != "ID" {
+			continue
+		}
+		switch v := e.Value.(type) {
+		case int32:
+			return int(v), true
+		case int64:
+			return int(v), true
+		case float64:
+			return int(v), true
+		}
+	}
+	return 0, false
+}
+
+func setField(doc bson.D, key string, value any) bson.D {
+	for i := range doc {
+		if doc[i].Key == key {
+			doc[i].Value = value
+			return doc
+		}
+	}
+	return append(doc, bson.E{Key: key, Value: value})
+}
+
+// upsertPost 按 ID 替换或插入文档；_id 不参与替换，避免与已有文档冲突
+func upsertPost(ctx context.Context, blogs Collection, id int, doc bson.D) error {
+	replacement := make(bson.D, 0, len(doc))
+	for _, e := range doc {
+		if e.Key != "_id" {
+			replacement = append(replacement, e)
+		}
+	}
+	_, err := blogs.ReplaceOne(ctx, bson.D{{Key: "ID", Value: id}}, replacement, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to restore blog %d: %v", id, err)
+	}
+	return nil
+}
+
+// restoreFile 将 src 写到 dst；dst 已存在且内容相同时跳过，返回是否实际写入
+func restoreFile(src, dst string, dryRun bool) (bool, error) {
+	if same, err := sameContent(src, dst); err != nil {
+		return false, err
+	} else if same {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return false, err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	// 先写临时文件再改名，避免中断时留下半截文件
+	tmp := dst + ".importing"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return false, err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func sameContent(a, b string) (bool, error) {
+	ha, err := fileSHA256(a)
+	if err != nil {
+		return false, err
+	}
+	hb, err := fileSHA256(b)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return ha == hb, nil
+}
+
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// weatherLocation 天气查询的位置
+type weatherLocation struct {
+	City, Region, CountryCode string
+	// Source 定位来源：query（请求给出坐标）、地理编码器名称、maxmind（本地库）或在线 IP 定位提供商名称
+	Source    string
+	Lat, Lon  float64
+	HaveCoord bool
+	// Local 为 true 表示客户端为私有或回环地址，无法定位
+	Local bool
+}
+
+// SourceQuery 坐标由请求参数 lat/lon 直接给出
+const SourceQuery = "query"
+
+// paramError 请求参数无效或位置无法解析，直接以 status 返回给客户端
+type paramError struct {
+	status int
+	msg    string
+}
+
+func (e *paramError) Error() string { return e.msg }
+
+// weatherLang 确定描述语言：优先 lang 参数，其次 Accept-Language，默认中文
+func weatherLang(r *http.Request) (string, *paramError) {
+	if v := r.URL.Query().Get("lang"); v != "" {
+		lang, ok := utils.ParseLang(v)
+		if !ok {
+			return "", &paramError{http.StatusBadRequest, "unsupported lang: " + v + " (zh, en)"}
+		}
+		return lang, nil
+	}
+	return negotiateLang(r.Header.Get("Accept-Language")), nil
+}
+
+// langRange Accept-Language 中的一个语言范围及其 q 值
+type langRange struct {
+	tag string
+	q   float64
+}
+
+// parseAcceptLanguage 解析 Accept-Language，按 q 值降序返回（同 q 值保持原顺序）。
+// q 缺省为 1；q 非法或超出 [0, 1] 的条目忽略
+func parseAcceptLanguage(header string) []langRange {
+	var out []langRange
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		q, ok := 1.0, true
+		for _, p := range strings.Split(params, ";") {
+			key, v, found := strings.Cut(p, "=")
+			if !found || strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			q, ok = f, err == nil && f >= 0 && f <= 1
+		}
+		if ok {
+			out = append(out, langRange{tag, q})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b langRange) int { return cmp.Compare(b.q, a.q) })
+	return out
+}
+
+// negotiateLang 按 Accept-Language 的 q 值选择支持的语言，只比较主标签（en-US 视为 en），
+// 同 q 值取靠前的。均不支持时为中文；中文被 q=0 明确拒绝时为英文
+func negotiateLang(header string) string {
+	refused := make(map[string]bool)
+	for _, r := range parseAcceptLanguage(header) {
+		lang, ok := utils.ParseLang(r.tag)
+		if !ok {
+			continue
+		}
+		// 已按 q 降序排列，q=0 的条目只会出现在所有可接受的条目之后
+		if r.q > 0 {
+			return lang
+		}
+		refused[lang] = true
+	}
+	if refused[utils.LangZH] && !refused[utils.LangEN] {
+		return utils.LangEN
+	}
+	return utils.LangZH
+}
+
+// weatherUnits 解析 units 参数，默认公制
+func weatherUnits(r *http.Request) (utils.UnitSystem, *paramError) {
+	v := r.URL.Query().Get("units")
+	if v == "" {
+		return utils.UnitsMetric, nil
+	}
+	u, ok := utils.ParseUnitSystem(v)
+	if !ok {
+		return "", &paramError{http.StatusBadRequest, "unsupported units: " + v + " (metric, imperial)"}
+	}
+	return u, nil
+}
+
+// unitLabels 响应中各数值字段的单位
+type unitLabels struct {
+	System        utils.UnitSystem `json:"system"`
+	Temperature   string           `json:"temperature"`
+	WindSpeed     string           `json:"windSpeed"`
+	Pressure      string           `json:"pressure"`
+	Precipitation string           `json:"precipitation"`
+}
+
+func newUnitLabels(u utils.UnitSystem) unitLabels {
+	return unitLabels{
+		System:        u,
+		Temperature:   u.TemperatureLabel(),
+		WindSpeed:     u.SpeedLabel(),
+		Pressure:      u.PressureLabel(),
+		Precipitation: u.PrecipitationLabel(),
+	}
+}
+
+// weatherOptions 解析 lang 与 units；两者都会影响响应内容，因此标记 Vary
+func weatherOptions(w http.ResponseWriter, r *http.Request) (string, utils.UnitSystem, *paramError) {
+	addVary(w.Header(), "Accept-Language")
+	lang, perr := weatherLang(r)
+	if perr != nil {
+		return "", "", perr
+	}
+	units, perr := weatherUnits(r)
+	if perr != nil {
+		return "", "", perr
+	}
+	return lang, units, nil
+}
+
+// resolveLocation 确定天气查询的位置：优先使用 lat/lon 参数，其次 city 参数（经地理编码，地名使用 lang），
+// 都未给出时根据客户端 IP 定位。定位或地理编码服务失败时返回的 failed 中包含 "ipgeo" 或 "geocoding"
+func resolveLocation(r *http.Request, lang string) (loc weatherLocation, failed []string, lerr *paramError) {
+	q := r.URL.Query()
+	latStr, lonStr, city := q.Get("lat"), q.Get("lon"), strings.TrimSpace(q.Get("city"))
+	switch {
+	case latStr != "" || lonStr != "":
+		lat, err1 := strconv.ParseFloat(latStr, 64)
+		lon, err2 := strconv.ParseFloat(lonStr, 64)
+		if err1 != nil || err2 != nil || math.IsNaN(lat) || math.IsNaN(lon) || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return loc, nil, &paramError{http.StatusBadRequest, "lat and lon must both be given, with -90<=lat<=90 and -180<=lon<=180"}
+		}
+		return weatherLocation{Source: SourceQuery, Lat: lat, Lon: lon, HaveCoord: true}, nil, nil
+	case city != "":
+		if len(city) > 100 {
+			return loc, nil, &paramError{http.StatusBadRequest, "city is too long"}
+		}
+		place, err := utils.DefaultGeocoder().Geocode(r.Context(), city, lang)
+		switch {
+		case errors.Is(err, utils.ErrPlaceNotFound):
+			return loc, nil, &paramError{http.StatusNotFound, "city not found: " + city}
+		case err != nil:
+			return weatherLocation{City: city}, []string{"geocoding"}, nil
+		}
+		return weatherLocation{
+			City:        place.Name,
+			Region:      place.Region,
+			CountryCode: place.CountryCode,
+			Source:      place.Source,
+			Lat:         place.Latitude,
+			Lon:         place.Longitude,
+			HaveCoord:   true,
+		}, nil, nil
+	}
+
+	ip := getClientIP(r)
+	if utils.IsPrivateOrLoopbackIP(ip) {
+		return weatherLocation{Local: true}, nil, nil
+	}
+	info, err := utils.LookupIPLocation(r.Context(), ip)
+	if err != nil {
+		return loc, []string{"ipgeo"}, nil
+	}
+	if info != nil {
+		loc.City, loc.Region, loc.CountryCode, loc.Source = info.City, info.Region, info.CountryCode, info.Source
+		if info.Latitude != 0 || info.Longitude != 0 {
+			loc.Lat, loc.Lon, loc.HaveCoord = info.Latitude, info.Longitude, true
+		}
+	}
+	return loc, nil, nil
+}
+
+// displayLocation 展示用位置字符串；只有坐标时显示坐标
+func (l weatherLocation) displayLocation() string {
+	if s := buildLocation(l.City, l.Region, l.CountryCode); s != "" {
+		return s
+	}
+	if l.HaveCoord {
+		return fmt.Sprintf("%.4f, %.4f", l.Lat, l.Lon)
+	}
+	return ""
+}
+
+// failedSources 从天气获取错误中提取失败的数据源；无法区分时视为 all 全部失败
+func failedSources(err error, all ...string) []string {
+	var werr *utils.WeatherError
+	var serr *utils.SourceError
+	switch {
+	case errors.As(err, &werr):
+		var names []string
+		for _, se := range werr.Errors {
+			names = append(names, se.Source)
+		}
+		return names
+	case errors.As(err, &serr):
+		return []string{serr.Source}
+	default:
+		return all
+	}
+}
+
+// getClientIP 提取客户端真实 IP（支持常见代理头），失败时回退 RemoteAddr
+func getClientIP(r *http.Request) string {
+	// 优先 Cloudflare
+	if ip := strings.TrimSpace(r.Header.Get("CF-Connecting-IP")); ip != "" {
+		return ip
+	}
+	// 再看 X-Forwarded-For 第一段
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		parts := strings.Split(xff, ",")
+		if len(parts) > 0 {
+			p := strings.TrimSpace(parts[0])
+			if p != "" {
+				return p
+			}
+		}
+	}
+	// 直接 RemoteAddr
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// beaufortLevel 近似计算蒲福风级
+func beaufortLevel(kmh int) int {
+	switch {
+	case kmh < 1:
+		return 0
+	case kmh <= 5:
+		return 1
+	case kmh <= 11:
+		return 2
+	case kmh <= 19:
+		return 3
+	case kmh <= 28:
+		return 4
+	case kmh <= 38:
+		return 5
+	case kmh <= 49:
+		return 6
+	case kmh <= 61:
+		return 7
+	case kmh <= 74:
+		return 8
+	case kmh <= 88:
+		return 9
+	case kmh <= 102:
+		return 10
+	case kmh <= 117:
+		return 11
+	default:
+		return 12
+	}
+}
+
+// buildLocation 拼接展示字符串（city · region · countryCode）
+func buildLocation(city, region, countryCode string) string {
+	var segs []string
+	if city != "" {
+		segs = append(segs, city)
+	}
+	if region != "" {
+		segs = append(segs, region)
+	}
+	if countryCode != "" {
+		segs = append(segs, countryCode)
+	}
+	return strings.Join(segs, " · ")
+}
+
+// WeatherHandler 返回天气与空气质量；位置由 lat/lon 或 city 参数指定，缺省按客户端 IP 定位
+func WeatherHandler(w http.ResponseWriter, r *http.Request) {
+	// 日志
+	log.Printf("\033[32m[Log]\033[0m------Method: %s\n", r.Method)
+	log.Printf("\033[32m[Log]\033[0m------Path: %s\n", r.URL.Path)
+	log.Printf("\033[32m[Log]\033[0m------User-Agent: %s\n", r.Header.Get("User-Agent"))
+
+	writeJSONHeaders(w)
+
+	type resp struct {
+		City         string `json:"city"`
+		Region       string `json:"region"`
+		CountryCode  string `json:"countryCode"`
+		Location     string `json:"location"`
+		TemperatureC *int   `json:"temperatureC"`
+		WindSpeedKmh *int   `json:"windSpeedKmh"`
+		WindLevel    *int   `json:"windLevel"`
+		Humidity     *int   `json:"humidity"`
+		AQIUS        *int   `json:"aqiUS"`
+		WeatherText  string `json:"weatherText"`
+		UpdatedAt    string `json:"updatedAt"`
+		// Temperature / WindSpeed 按 units 换算，单位见 Units；temperatureC / windSpeedKmh 始终为公制
+		Temperature *int       `json:"temperature"`
+		WindSpeed   *int       `json:"windSpeed"`
+		Lang        string     `json:"lang"`
+		Units       unitLabels `json:"units"`
+		// ApparentTemperature / WindGust / Pressure / Precipitation 同样按 units 换算
+		ApparentTemperature *int `json:"apparentTemperature"`
+		// WindDirection 风的来向（度，0 为正北），WindDirectionCompass 为 16 方位缩写
+		WindDirection        *int     `json:"windDirection"`
+		WindDirectionCompass string   `json:"windDirectionCompass,omitempty"`
+		WindDirectionText    string   `json:"windDirectionText,omitempty"`
+		WindGust             *int     `json:"windGust"`
+		UVIndex              *float64 `json:"uvIndex"`
+		Pressure             *float64 `json:"pressure"`
+		Precipitation        *float64 `json:"precipitation"`
+		CloudCover           *int     `json:"cloudCover"`
+		IsDay                *bool    `json:"isDay"`
+		// Icon 由天气代码与昼夜得到的稳定图标标识，如 clear-night、rain-showers-day
+		Icon string `json:"icon"`
+		// LocationSource 定位来源：maxmind（本地库）或在线提供商名称
+		LocationSource string `json:"locationSource,omitempty"`
+		// CacheAgeSeconds 天气数据距获取时的秒数（来自缓存时大于 0）
+		CacheAgeSeconds *int `json:"cacheAgeSeconds,omitempty"`
+		// Degraded 为 true 时部分数据缺失，FailedSources 列出失败的上游
+		Degraded      bool     `json:"degraded"`
+		FailedSources []string `json:"failedSources,omitempty"`
+	}
+
+	lang, units, perr := weatherOptions(w, r)
+	if perr != nil {
+		writeJSONError(w, perr.status, perr.msg)
+		return
+	}
+	loc, failed, lerr := resolveLocation(r, lang)
+	if lerr != nil {
+		writeJSONError(w, lerr.status, lerr.msg)
+		return
+	}
+	if loc.Local {
+		json.NewEncoder(w).Encode(resp{
+			City:        "localhost",
+			Location:    "localhost",
+			WeatherText: "N/A",
+			UpdatedAt:   time.Now().UTC().Format(time.RFC3339),
+			Lang:        lang,
+			Units:       newUnitLabels(units),
+			Icon:        utils.WeatherIcon(nil, nil),
+		})
+		return
+	}
+
+	// 请求天气
+	var (
+		weatherText                                  = utils.WeatherDescription(nil, lang)
+		tempPtr, windPtr, windLvlPtr, humPtr, aqiPtr *int
+		temp, wind                                   *int
+		cacheAge                                     *int
+		out                                          = resp{Icon: utils.WeatherIcon(nil, nil)}
+	)
+	if loc.HaveCoord {
+		wdata, err := utils.DefaultWeatherCache().Current(r.Context(), loc.Lat, loc.Lon)
+		if err != nil {
+			failed = append(failed, failedSources(err, utils.SourceForecast, utils.SourceAirQuality)...)
+		}
+		if err == nil && wdata != nil {
+			failed = append(failed, wdata.FailedSources()...)
+			age := int(time.Since(wdata.FetchedAt).Seconds())
+			cacheAge = &age
+			// 公制字段与换算后的字段同样四舍五入，公制下 temperature 与 temperatureC 一致
+			tempPtr = roundPtr(wdata.TempC)
+			temp = convertPtr(wdata.TempC, units.Temperature)
+			windPtr = roundPtr(wdata.WindSpeedKmh)
+			if windPtr != nil {
+				lvl := beaufortLevel(*windPtr)
+				windLvlPtr = &lvl
+			}
+			wind = convertPtr(wdata.WindSpeedKmh, units.Speed)
+			humPtr = roundPtr(wdata.Humidity)
+			aqiPtr = roundPtr(wdata.AQIUS)
+			weatherText = utils.WeatherDescription(wdata.WeatherCode, lang)
+
+			// 体感、风向、阵风、紫外线、气压、降水、云量与昼夜
+			pressureDecimals, precipDecimals := 0, 1
+			if units == utils.UnitsImperial {
+				pressureDecimals, precipDecimals = 2, 2
+			}
+			out.ApparentTemperature = convertPtr(wdata.ApparentTempC, units.Temperature)
+			if wdata.WindDirectionDeg != nil {
+				out.WindDirection = roundPtr(wdata.WindDirectionDeg)
+				out.WindDirectionCompass = utils.CompassPoint(*wdata.WindDirectionDeg)
+				out.WindDirectionText = utils.CompassText(out.WindDirectionCompass, lang)
+			}
+			out.WindGust = convertPtr(wdata.WindGustsKmh, units.Speed)
+			out.UVIndex = roundTo(wdata.UVIndex, identity, 1)
+			out.Pressure = roundTo(wdata.PressureHPa, units.Pressure, pressureDecimals)
+			out.Precipitation = roundTo(wdata.PrecipitationMm, units.Precipitation, precipDecimals)
+			out.CloudCover = roundPtr(wdata.CloudCover)
+			out.IsDay = wdata.IsDay
+			out.Icon = utils.WeatherIcon(wdata.WeatherCode, wdata.IsDay)
+		}
+	}
+
+	out.City = loc.City
+	out.Region = loc.Region
+	out.CountryCode = loc.CountryCode
+	out.Location = loc.displayLocation()
+	out.TemperatureC = tempPtr
+	out.WindSpeedKmh = windPtr
+	out.WindLevel = windLvlPtr
+	out.Humidity = humPtr
+	out.AQIUS = aqiPtr
+	out.WeatherText = weatherText
+	out.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	out.Temperature = temp
+	out.WindSpeed = wind
+	out.Lang = lang
+	out.Units = newUnitLabels(units)
+	out.LocationSource = loc.Source
+	out.CacheAgeSeconds = cacheAge
+	out.Degraded = len(failed) > 0
+	out.FailedSources = failed
+	json.NewEncoder(w).Encode(out)
+}
+
+// identity 不做单位换算
+func identity(v float64) float64 { return v }
+
+// ForecastHandler 返回未来 24 小时逐小时与 7 天逐日预报，位置参数同 WeatherHandler
+func ForecastHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("\033[32m[Log]\033[0m------Method: %s\n", r.Method)
+	log.Printf("\033[32m[Log]\033[0m------Path: %s\n", r.URL.Path)
+
+	writeJSONHeaders(w)
+
+	type hourly struct {
+		Time                     time.Time `json:"time"`
+		TemperatureC             *int      `json:"temperatureC"`
+		Temperature              *int      `json:"temperature"`
+		PrecipitationProbability *int      `json:"precipitationProbability"`
+		WeatherCode              *int      `json:"weatherCode"`
+		WeatherText              string    `json:"weatherText"`
+	}
+	type daily struct {
+		Date                     string     `json:"date"`
+		TemperatureMinC          *int       `json:"temperatureMinC"`
+		TemperatureMaxC          *int       `json:"temperatureMaxC"`
+		TemperatureMin           *int       `json:"temperatureMin"`
+		TemperatureMax           *int       `json:"temperatureMax"`
+		PrecipitationProbability *int       `json:"precipitationProbability"`
+		WeatherCode              *int       `json:"weatherCode"`
+		WeatherText              string     `json:"weatherText"`
+		Sunrise                  *time.Time `json:"sunrise"`
+		Sunset                   *time.Time `json:"sunset"`
+	}
+	type resp struct {
+		City            string     `json:"city"`
+		Region          string     `json:"region"`
+		CountryCode     string     `json:"countryCode"`
+		Location        string     `json:"location"`
+		LocationSource  string     `json:"locationSource,omitempty"`
+		Timezone        string     `json:"timezone,omitempty"`
+		Lang            string     `json:"lang"`
+		Units           unitLabels `json:"units"`
+		Hourly          []hourly   `json:"hourly"`
+		Daily           []daily    `json:"daily"`
+		UpdatedAt       string     `json:"updatedAt"`
+		CacheAgeSeconds *int       `json:"cacheAgeSeconds,omitempty"`
+		Degraded        bool       `json:"degraded"`
+		FailedSources   []string   `json:"failedSources,omitempty"`
+	}
+
+	lang, units, perr := weatherOptions(w, r)
+	if perr != nil {
+		writeJSONError(w, perr.status, perr.msg)
+		return
+	}
+	out := resp{Hourly: []hourly{}, Daily: []daily{}, Lang: lang, Units: newUnitLabels(units)}
+	loc, failed, lerr := resolveLocation(r, lang)
+	if lerr != nil {
+		writeJSONError(w, lerr.status, lerr.msg)
+		return
+	}
+	if loc.Local {
+		out.City, out.Location = "localhost", "localhost"
+	} else {
+		out.City, out.Region, out.CountryCode = loc.City, loc.Region, loc.CountryCode
+		out.Location = loc.displayLocation()
+		out.LocationSource = loc.Source
+	}
+
+	if loc.HaveCoord {
+		fc, err := utils.DefaultWeatherCache().Forecast(r.Context(), loc.Lat, loc.Lon)
+		if err != nil {
+			failed = append(failed, failedSources(err, utils.SourceForecast)...)
+		} else {
+			now := time.Now()
+			age := int(now.Sub(fc.FetchedAt).Seconds())
+			out.CacheAgeSeconds = &age
+			out.Timezone = fc.Timezone
+			for _, h := range fc.NextHours(now, utils.ForecastHours) {
+				out.Hourly = append(out.Hourly, hourly{
+					Time:                     h.Time,
+					TemperatureC:             roundPtr(h.TempC),
+					Temperature:              convertPtr(h.TempC, units.Temperature),
+					PrecipitationProbability: roundPtr(h.PrecipitationProbability),
+					WeatherCode:              h.WeatherCode,
+					WeatherText:              utils.WeatherDescription(h.WeatherCode, lang),
+				})
+			}
+			for _, d := range fc.DaysFrom(now, utils.ForecastDays) {
+				out.Daily = append(out.Daily, daily{
+					Date:                     d.Date,
+					TemperatureMinC:          roundPtr(d.TempMinC),
+					TemperatureMaxC:          roundPtr(d.TempMaxC),
+					TemperatureMin:           convertPtr(d.TempMinC, units.Temperature),
+					TemperatureMax:           convertPtr(d.TempMaxC, units.Temperature),
+					PrecipitationProbability: roundPtr(d.PrecipitationProbability),
+					WeatherCode:              d.WeatherCode,
+					WeatherText:              utils.WeatherDescription(d.WeatherCode, lang),
+					Sunrise:                  d.Sunrise,
+					Sunset:                   d.Sunset,
+				})
+			}
+		}
+	}
+
+	out.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	out.Degraded = len(failed) > 0
+	out.FailedSources = failed
+	json.NewEncoder(w).Encode(out)
+}
+
+// roundPtr 四舍五入为整数，nil 保持为 nil
+func roundPtr(v *float64) *int {
+	if v == nil {
+		return nil
+	}
+	n := int(math.Round(*v))
+	return &n
+}
+
+// roundTo 单位换算后保留 decimals 位小数，nil 保持为 nil
+func roundTo(v *float64, convert func(float64) float64, decimals int) *float64 {
+	if v == nil {
+		return nil
+	}
+	p := math.Pow10(decimals)
+	c := math.Round(convert(*v)*p) / p
+	return &c
+}
+
+// convertPtr 单位换算后四舍五入，nil 保持为 nil
+func convertPtr(v *float64, convert func(float64) float64) *int {
+	if v == nil {
+		return nil
+	}
+	c := convert(*v)
+	return roundPtr(&c)
+}
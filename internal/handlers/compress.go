@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressConfig 响应压缩配置
+type CompressConfig struct {
+	// MinSize 小于该字节数的响应不压缩
+	MinSize int
+	// Types 允许压缩的 Content-Type；以 "/" 结尾的表示前缀（如 "text/"）
+	Types []string
+}
+
+var defaultCompressTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/manifest+json",
+	"image/svg+xml",
+}
+
+// CompressConfigFromEnv 读取 COMPRESS_MIN_SIZE / COMPRESS_TYPES（逗号分隔）
+func CompressConfigFromEnv() CompressConfig {
+	cfg := CompressConfig{MinSize: 1024, Types: defaultCompressTypes}
+	if v := os.Getenv("COMPRESS_MIN_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.MinSize = n
+		}
+	}
+	if v := os.Getenv("COMPRESS_TYPES"); v != "" {
+		var types []string
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+				types = append(types, t)
+			}
+		}
+		cfg.Types = types
+	}
+	return cfg
+}
+
+// allowed 判断 Content-Type 是否在允许列表内
+func (c CompressConfig) allowed(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range c.Types {
+		if mt == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mt, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// encoder 三种压缩器的公共接口，Reset 后可复用
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// encodingPreference 客户端 q 值相同时的服务端偏好顺序
+var encodingPreference = []string{"br", "zstd", "gzip"}
+
+// encoderPools 按编码复用压缩器，避免每个请求重新分配窗口与哈希表
+var encoderPools = map[string]*sync.Pool{
+	"br": {New: func() any {
+		return brotli.NewWriterLevel(nil, 5)
+	}},
+	"zstd": {New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+	"gzip": {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// parseAcceptEncoding 解析 Accept-Encoding 为 编码→q 值；star 为 "*" 的 q 值，未出现时为 -1
+func parseAcceptEncoding(header string) (qs map[string]float64, star float64) {
+	qs = make(map[string]float64)
+	star = -1
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(p), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if name == "*" {
+			star = q
+		} else if name != "" {
+			qs[name] = q
+		}
+	}
+	return qs, star
+}
+
+// acceptsEncoding 判断 Accept-Encoding 是否接受指定编码（q=0 表示拒绝）
+func acceptsEncoding(header, encoding string) bool {
+	qs, star := parseAcceptEncoding(header)
+	if q, ok := qs[encoding]; ok {
+		return q > 0
+	}
+	return star > 0
+}
+
+// negotiateEncoding 按 Accept-Encoding 的 q 值选择编码，均不可用时返回 ""
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	qs, star := parseAcceptEncoding(header)
+	best, bestQ := "", 0.0
+	for _, enc := range encodingPreference {
+		q, ok := qs[enc]
+		if !ok {
+			if star < 0 {
+				continue
+			}
+			q = star
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// addVary 向 Vary 追加字段，已存在时不重复
+func addVary(h http.Header, field string) {
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
+
+// Compress 按 Accept-Encoding 协商 br/zstd/gzip 压缩响应。
+// 已设置 Content-Encoding、部分内容、过小或类型不在允许列表内的响应原样发送。
+// 压缩后强 ETag 追加 "-<编码>" 后缀，请求中的 If-None-Match 会先去掉后缀再交给下游比较
+func Compress(cfg CompressConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addVary(w.Header(), "Accept-Encoding")
+		enc := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if enc == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, cfg: cfg, enc: enc, status: http.StatusOK}
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			stripped, suffix := stripETagSuffixes(inm)
+			if suffix != "" {
+				r.Header.Set("If-None-Match", stripped)
+				cw.clientSuffix = suffix
+			}
+		}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// stripETagSuffixes 去掉 If-None-Match 中各 ETag 的编码后缀，返回处理结果与遇到的第一个后缀
+func stripETagSuffixes(header string) (string, string) {
+	parts := strings.Split(header, ",")
+	first := ""
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		for _, enc := range encodingPreference {
+			suffix := "-" + enc + `"`
+			if strings.HasSuffix(part, suffix) {
+				part = strings.TrimSuffix(part, suffix) + `"`
+				if first == "" {
+					first = enc
+				}
+				break
+			}
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, ", "), first
+}
+
+// withETagSuffix 为强 ETag 追加编码后缀；弱 ETag 不区分编码，保持不变
+func withETagSuffix(h http.Header, enc string) {
+	etag := h.Get("ETag")
+	if etag == "" || strings.HasPrefix(etag, "W/") || !strings.HasSuffix(etag, `"`) {
+		return
+	}
+	h.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+enc+`"`)
+}
+
+// compressWriter 先缓冲至 MinSize 再决定是否压缩
+type compressWriter struct {
+	http.ResponseWriter
+	cfg          CompressConfig
+	enc          string
+	clientSuffix string // 客户端 If-None-Match 中携带的编码后缀
+
+	status  int
+	buf     []byte
+	decided bool
+	w       encoder // nil 表示不压缩
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+	if c.decided || code < 200 {
+		if code < 200 {
+			c.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+	c.status = code
+	// 无正文的响应立即发送
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		c.decide()
+	}
+}
+
+func (c *compressWriter) Write(b []byte) (int, error) {
+	if c.decided {
+		if c.w != nil {
+			return c.w.Write(b)
+		}
+		return c.ResponseWriter.Write(b)
+	}
+	c.buf = append(c.buf, b...)
+	if len(c.buf) >= c.cfg.MinSize {
+		if err := c.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// decide 根据状态码、响应头与已缓冲数据决定是否压缩，并发送响应头与缓冲区
+func (c *compressWriter) decide() error {
+	c.decided = true
+	h := c.Header()
+	if h.Get("Content-Type") == "" && len(c.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(c.buf))
+	}
+	compress := c.status != http.StatusNoContent &&
+		c.status != http.StatusNotModified &&
+		c.status != http.StatusPartialContent &&
+		h.Get("Content-Encoding") == "" &&
+		h.Get("Content-Range") == "" &&
+		len(c.buf) >= c.cfg.MinSize &&
+		c.cfg.allowed(h.Get("Content-Type"))
+
+	switch {
+	case compress:
+		h.Set("Content-Encoding", c.enc)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		withETagSuffix(h, c.enc)
+		c.w = encoderPools[c.enc].Get().(encoder)
+		c.w.Reset(c.ResponseWriter)
+	case c.status == http.StatusNotModified && c.clientSuffix != "":
+		// 客户端缓存的是压缩版本，304 中回送相同的 ETag
+		withETagSuffix(h, c.clientSuffix)
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+
+	if len(c.buf) == 0 {
+		return nil
+	}
+	buf := c.buf
+	c.buf = nil
+	var err error
+	if c.w != nil {
+		_, err = c.w.Write(buf)
+	} else {
+		_, err = c.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush 支持流式响应：立即决定编码并刷新压缩器
+func (c *compressWriter) Flush() {
+	if !c.decided {
+		c.decide()
+	}
+	if c.w != nil {
+		c.w.Flush()
+	}
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// close 结束响应并归还压缩器
+func (c *compressWriter) close() {
+	if !c.decided {
+		c.decide()
+	}
+	if c.w != nil {
+		c.w.Close()
+		c.w.Reset(io.Discard)
+		encoderPools[c.enc].Put(c.w)
+		c.w = nil
+	}
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
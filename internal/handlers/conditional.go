@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultCacheControl 允许缓存，但每次使用前须携带 ETag 重新验证
+const defaultCacheControl = "public, no-cache"
+
+// CachePolicy 各路由响应的 Cache-Control 值，未配置的路由使用 defaultCacheControl
+type CachePolicy map[string]string
+
+// CachePolicyFromEnv 读取 CACHE_CONTROL_BLOG / CACHE_CONTROL_LATEST_BLOG / CACHE_CONTROL_BLOG_DETAIL
+func CachePolicyFromEnv() CachePolicy {
+	p := CachePolicy{}
+	for route, key := range map[string]string{
+		"/api/Blog":       "CACHE_CONTROL_BLOG",
+		"/api/LatestBlog": "CACHE_CONTROL_LATEST_BLOG",
+		"/api/BlogDetail": "CACHE_CONTROL_BLOG_DETAIL",
+	} {
+		if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+			p[route] = v
+		}
+	}
+	return p
+}
+
+func (p CachePolicy) header(route string) string {
+	if v, ok := p[route]; ok {
+		return v
+	}
+	return defaultCacheControl
+}
+
+// writeCachedJSON 序列化 v 并附带强 ETag（内容哈希）与 Last-Modified；
+// 条件请求命中时返回 304 而不发送正文。lastModified 为零值时不设置 Last-Modified
+func (s *Server) writeCachedJSON(w http.ResponseWriter, r *http.Request, route string, v any, lastModified time.Time) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		log.Printf("Error encoding response: %v", err)
+		return
+	}
+	body = append(body, '\n')
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", s.cachePolicy.header(route))
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, lastModified) {
+		h.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(body)
+}
+
+// notModified 按 RFC 9110 判断条件请求：If-None-Match 存在时忽略 If-Modified-Since
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// HTTP 日期只精确到秒
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+// etagMatch 对 If-None-Match 列表做弱比较（忽略 W/ 前缀），支持 "*"
+func etagMatch(header, etag string) bool {
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "*" || strings.TrimPrefix(part, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
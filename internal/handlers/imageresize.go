@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/images"
+	"golang.org/x/sync/singleflight"
+)
+
+// ImageResizeConfig 图片缩放与格式转换配置
+type ImageResizeConfig struct {
+	// StaticDir 源图目录
+	StaticDir string
+	// CacheDir 缩放结果的磁盘缓存目录
+	CacheDir string
+	// CacheMaxBytes 磁盘缓存总大小上限，超出时按最近使用时间淘汰；0 表示不限制
+	CacheMaxBytes int64
+	// SweepInterval 两次容量淘汰的最小间隔；淘汰在后台遍历缓存目录，不阻塞请求
+	SweepInterval time.Duration
+	// EvictGrace 修改时间在此之内的变体不淘汰，避免刚生成或刚被使用的文件被删
+	EvictGrace time.Duration
+	// Widths / Qualities 允许请求的宽度与 JPEG 质量，防止任意参数耗尽 CPU 与磁盘。
+	// q 只对 JPEG 输出有效，对无损输出指定 q 会被拒绝
+	Widths    []int
+	Qualities []int
+	// DefaultQuality 未指定 q 时使用
+	DefaultQuality int
+	// MaxPixels 源图宽×高上限
+	MaxPixels int
+	// CacheControl 源文件名不带指纹时的 Cache-Control
+	CacheControl string
+}
+
+// ImageResizeConfigFromEnv 读取 IMAGE_CACHE_DIR / IMAGE_CACHE_MAX_MB / IMAGE_CACHE_SWEEP_INTERVAL /
+// IMAGE_CACHE_EVICT_GRACE / IMAGE_WIDTHS / IMAGE_QUALITIES
+func ImageResizeConfigFromEnv(staticDir, cacheControl string) ImageResizeConfig {
+	cfg := ImageResizeConfig{
+		StaticDir:      staticDir,
+		CacheDir:       os.Getenv("IMAGE_CACHE_DIR"),
+		CacheMaxBytes:  1 << 30,
+		SweepInterval:  time.Minute,
+		EvictGrace:     10 * time.Minute,
+		Widths:         envInts("IMAGE_WIDTHS", []int{160, 320, 640, 960, 1280, 1920}),
+		Qualities:      envInts("IMAGE_QUALITIES", []int{50, 75, 90}),
+		DefaultQuality: 75,
+		MaxPixels:      40_000_000,
+		CacheControl:   cacheControl,
+	}
+	if cfg.CacheDir == "" {
+		cfg.CacheDir = "data/imgcache"
+	}
+	if v := os.Getenv("IMAGE_CACHE_MAX_MB"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.CacheMaxBytes = int64(n) << 20
+		}
+	}
+	if d, err := time.ParseDuration(os.Getenv("IMAGE_CACHE_SWEEP_INTERVAL")); err == nil && d >= 0 {
+		cfg.SweepInterval = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("IMAGE_CACHE_EVICT_GRACE")); err == nil && d >= 0 {
+		cfg.EvictGrace = d
+	}
+	if !slices.Contains(cfg.Qualities, cfg.DefaultQuality) && len(cfg.Qualities) > 0 {
+		cfg.DefaultQuality = cfg.Qualities[len(cfg.Qualities)/2]
+	}
+	return cfg
+}
+
+// envInts 解析逗号分隔的正整数列表，为空或非法时返回默认值
+func envInts(key string, def []int) []int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	var out []int
+	for _, s := range strings.Split(v, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n <= 0 {
+			return def
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// ImageResizer 按 w/q/fmt 参数输出缩放或转换格式后的图片，结果缓存到磁盘。
+// 可挂载在 /img/ 下，也可通过 Middleware 让 /static/ 的图片请求支持同样的参数
+type ImageResizer struct {
+	cfg       ImageResizeConfig
+	group     singleflight.Group
+	sem       chan struct{} // 限制同时进行的解码/编码数量
+	sweeping  atomic.Bool   // 同一时间只进行一次容量淘汰
+	lastSweep atomic.Int64  // 上次开始淘汰的时间（UnixNano）
+}
+
+// NewImageResizer 创建缩放处理器，需配合 http.StripPrefix 使用
+func NewImageResizer(cfg ImageResizeConfig) *ImageResizer {
+	return &ImageResizer{cfg: cfg, sem: make(chan struct{}, runtime.NumCPU())}
+}
+
+// Middleware 请求带 w/q/fmt 参数时交给缩放处理，否则交给 next
+func (z *ImageResizer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Has("w") || q.Has("q") || q.Has("fmt") {
+			z.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// resizeParams 校验后的缩放参数
+type resizeParams struct {
+	width   int // 0 表示保持原宽
+	quality int // 无损格式为 0
+	format  string
+}
+
+// parseParams 校验查询参数；sourceFormat 用于推导默认输出格式。
+// WebP 只能无损编码，JPEG 源图转 WebP 只会更大，因此拒绝；q 只对 JPEG 输出有效
+func (z *ImageResizer) parseParams(r *http.Request, sourceFormat string) (resizeParams, error) {
+	q := r.URL.Query()
+	p := resizeParams{quality: z.cfg.DefaultQuality}
+	if v := q.Get("w"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || !slices.Contains(z.cfg.Widths, n) {
+			return p, fmt.Errorf("width must be one of %v", z.cfg.Widths)
+		}
+		p.width = n
+	}
+	if v := q.Get("q"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || !slices.Contains(z.cfg.Qualities, n) {
+			return p, fmt.Errorf("quality must be one of %v", z.cfg.Qualities)
+		}
+		p.quality = n
+	}
+	switch f := strings.ToLower(q.Get("fmt")); f {
+	case "":
+		p.format = sourceFormat
+		if p.format == images.GIF {
+			p.format = images.PNG
+		}
+	case "jpg", "jpeg":
+		p.format = images.JPEG
+	case images.PNG, images.WebP:
+		p.format = f
+	default:
+		return p, errors.New("fmt must be one of jpeg, png, webp")
+	}
+	if p.format == images.WebP && sourceFormat == images.JPEG {
+		return p, errors.New("fmt=webp is lossless and not offered for JPEG sources, use fmt=jpeg")
+	}
+	if images.Lossless(p.format) {
+		if q.Has("q") {
+			return p, errors.New("q only applies to jpeg output")
+		}
+		p.quality = 0
+	}
+	return p, nil
+}
+
+func (z *ImageResizer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	name := path.Clean("/" + r.URL.Path)
+	if hiddenPath(name) {
+		writeJSONError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	f, st, err := openRegular(filepath.Join(z.cfg.StaticDir, filepath.FromSlash(name)))
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	format, err := images.Sniff(head[:n])
+	if err != nil {
+		f.Close()
+		writeJSONError(w, http.StatusUnsupportedMediaType, "Not an image")
+		return
+	}
+	p, err := z.parseParams(r, format)
+	if err != nil {
+		f.Close()
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 同一源图的所有变体放在同一目录下，文件名以源图版本（mtime、大小）开头，
+	// 源图更新后生成新版本时顺带删除旧版本的变体
+	dir := filepath.Join(z.cfg.CacheDir, shortHash(name)[:2], shortHash(name))
+	version := shortHash(fmt.Sprintf("%d|%d", st.ModTime().UnixNano(), st.Size()))
+	key := version + "-" + shortHash(fmt.Sprintf("%d|%d|%s", p.width, p.quality, p.format))
+	cached := filepath.Join(dir, key+images.Ext(p.format))
+	// 命中时直接读缓存文件；未命中（或文件刚被淘汰）时重新生成，并从生成的字节输出，不再回读磁盘
+	var body io.ReadSeeker
+	if out, _, err := openRegular(cached); err == nil {
+		defer out.Close()
+		touchVariant(cached)
+		body = out
+	} else {
+		v, err, _ := z.group.Do(cached, func() (any, error) {
+			data, err := z.render(f, cached, p)
+			if err != nil {
+				return nil, err
+			}
+			pruneStaleVariants(dir, version)
+			z.startSweep()
+			return data, nil
+		})
+		if err != nil {
+			f.Close()
+			log.Printf("\033[31m[Image]\033[0m resize %s failed: %v", name, err)
+			writeJSONError(w, http.StatusUnprocessableEntity, "Cannot process image")
+			return
+		}
+		body = bytes.NewReader(v.([]byte))
+	}
+	f.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", images.ContentType(p.format))
+	h.Set("ETag", `"`+shortHash(name)+"-"+key+`"`)
+	if isFingerprinted(path.Base(name)) {
+		h.Set("Cache-Control", immutableCacheControl)
+	} else if z.cfg.CacheControl != "" {
+		h.Set("Cache-Control", z.cfg.CacheControl)
+	}
+	http.ServeContent(w, r, "", st.ModTime(), body)
+}
+
+// render 解码源图、缩放并编码，原子地写入缓存文件，返回编码结果
+func (z *ImageResizer) render(src *os.File, dst string, p resizeParams) ([]byte, error) {
+	z.sem <- struct{}{}
+	defer func() { <-z.sem }()
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := images.Config(data)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > z.cfg.MaxPixels {
+		return nil, errors.New("source image too large")
+	}
+	img, _, err := images.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := images.Encode(&buf, images.Resize(img, p.width), p.format, p.quality); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// shortHash 取 SHA-256 的前 16 位十六进制，用作缓存目录与文件名
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// pruneStaleVariants 删除 dir 中不属于源图当前版本的变体
+func pruneStaleVariants(dir, version string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if n := e.Name(); !strings.HasPrefix(n, version+"-") && !strings.HasPrefix(n, ".tmp-") {
+			os.Remove(filepath.Join(dir, n))
+		}
+	}
+}
+
+// touchIdle 命中时刷新 mtime 的最小间隔，避免每次命中都写文件系统
+const touchIdle = time.Hour
+
+// touchVariant 命中缓存时刷新 mtime，作为容量淘汰的最近使用时间
+func touchVariant(p string) {
+	if st, err := os.Stat(p); err == nil && time.Since(st.ModTime()) > touchIdle {
+		now := time.Now()
+		os.Chtimes(p, now, now)
+	}
+}
+
+// startSweep 在后台启动一次容量淘汰；距上次不足 SweepInterval 或已有淘汰在进行时跳过
+func (z *ImageResizer) startSweep() {
+	if z.cfg.CacheMaxBytes <= 0 {
+		return
+	}
+	if time.Since(time.Unix(0, z.lastSweep.Load())) < z.cfg.SweepInterval || !z.sweeping.CompareAndSwap(false, true) {
+		return
+	}
+	z.lastSweep.Store(time.Now().UnixNano())
+	go func() {
+		defer z.sweeping.Store(false)
+		z.sweep()
+	}()
+}
+
+// sweep 缓存总大小超过 CacheMaxBytes 时按 mtime 从旧到新删除变体，EvictGrace 内的变体保留。
+// 源图被删除后留下的变体也由这里回收
+func (z *ImageResizer) sweep() {
+	type variant struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var (
+		files []variant
+		total int64
+	)
+	filepath.WalkDir(z.cfg.CacheDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files = append(files, variant{p, info.Size(), info.ModTime()})
+			total += info.Size()
+		}
+		return nil
+	})
+	if total <= z.cfg.CacheMaxBytes {
+		return
+	}
+	slices.SortFunc(files, func(a, b variant) int { return a.modTime.Compare(b.modTime) })
+	cutoff := time.Now().Add(-z.cfg.EvictGrace)
+	for _, f := range files {
+		// 按 mtime 升序，遇到宽限期内的文件后其余也都在宽限期内
+		if total <= z.cfg.CacheMaxBytes || f.modTime.After(cutoff) {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+			os.Remove(filepath.Dir(f.path)) // 目录为空时一并删除
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/LtePrince/Personal-Website-backend/api"
+)
+
+// immutableCacheControl 文件名带内容指纹的资源内容永不变化，可长期缓存
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// precompressed 预压缩兄弟文件的扩展名与对应编码，按偏好排序
+var precompressed = []struct{ ext, encoding string }{
+	{".br", "br"},
+	{".gz", "gzip"},
+}
+
+// StaticHandler 服务 STATIC_DIR 下的文件：不列目录、隐藏点文件、
+// 指纹文件名使用 immutable 缓存，客户端支持时优先返回 .br/.gz 预压缩版本
+type StaticHandler struct {
+	dir          string
+	cacheControl string // 非指纹文件的 Cache-Control
+}
+
+// NewStaticHandler 创建静态资源处理器，需配合 http.StripPrefix 使用
+func NewStaticHandler(dir, cacheControl string) *StaticHandler {
+	return &StaticHandler{dir: dir, cacheControl: cacheControl}
+}
+
+func (s *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	name := path.Clean("/" + r.URL.Path)
+	if hiddenPath(name) {
+		writeJSONError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	full := filepath.Join(s.dir, filepath.FromSlash(name))
+	f, st, err := openRegular(full)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	defer f.Close()
+
+	h := w.Header()
+	addVary(h, "Accept-Encoding")
+	if ctype := mime.TypeByExtension(filepath.Ext(full)); ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	if isFingerprinted(path.Base(name)) {
+		h.Set("Cache-Control", immutableCacheControl)
+	} else if s.cacheControl != "" {
+		h.Set("Cache-Control", s.cacheControl)
+	}
+
+	// 预压缩版本：Range 请求仍使用原文件，避免字节区间落在压缩数据上
+	if r.Header.Get("Range") == "" {
+		accept := r.Header.Get("Accept-Encoding")
+		for _, p := range precompressed {
+			if !acceptsEncoding(accept, p.encoding) {
+				continue
+			}
+			cf, cst, err := openRegular(full + p.ext)
+			if err != nil {
+				continue
+			}
+			defer cf.Close()
+			f, st = cf, cst
+			h.Set("Content-Encoding", p.encoding)
+			if h.Get("Content-Type") == "" {
+				// 未知类型不能再对压缩数据做内容嗅探
+				h.Set("Content-Type", "application/octet-stream")
+			}
+			break
+		}
+	}
+
+	// 由 mtime 与大小派生的 ETag，预压缩版本自然不同；十六进制不会与压缩中间件的编码后缀混淆
+	h.Set("ETag", `"`+strconv.FormatInt(st.ModTime().UnixNano(), 16)+"-"+strconv.FormatInt(st.Size(), 16)+`"`)
+	http.ServeContent(w, r, name, st.ModTime(), f)
+}
+
+// openRegular 打开普通文件，目录或其它类型均视为不存在
+func openRegular(p string) (*os.File, os.FileInfo, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := f.Stat()
+	if err != nil || !st.Mode().IsRegular() {
+		f.Close()
+		return nil, nil, os.ErrNotExist
+	}
+	return f, st, nil
+}
+
+// hiddenPath 路径中任一段以 "." 开头即视为隐藏
+func hiddenPath(p string) bool {
+	for _, seg := range strings.Split(p, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isFingerprinted 判断文件名是否带内容指纹，如 app.3f9a1c2e.js、index-B3xYz12a.css；
+// 指纹段需 8~64 位且同时含字母与数字，避免把普通单词或纯数字时间戳当作指纹
+func isFingerprinted(name string) bool {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	i := strings.LastIndexAny(stem, ".-")
+	if i < 0 {
+		return false
+	}
+	seg := stem[i+1:]
+	if len(seg) < 8 || len(seg) > 64 {
+		return false
+	}
+	var letter, digit bool
+	for _, c := range seg {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			letter = true
+		case c == '_':
+		default:
+			return false
+		}
+	}
+	return letter && digit
+}
+
+// writeJSONError 以 api.Error 格式返回错误
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSONHeaders(w)
+	w.Header().Del("Content-Encoding")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(api.Error{Code: status, Message: message})
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/LtePrince/Personal-Website-backend/api"
+	"github.com/LtePrince/Personal-Website-backend/internal/images"
+)
+
+// UploadVariant 上传时额外生成的缩放版本
+type UploadVariant struct {
+	Name  string
+	Width int
+}
+
+// UploadConfig 图片上传配置
+type UploadConfig struct {
+	// Dir 写入目录，即 STATIC_DIR
+	Dir string
+	// AdminToken 为空时上传接口关闭
+	AdminToken string
+	// MaxBytes 单个文件大小上限
+	MaxBytes int64
+	// MaxPixels 宽×高上限，防止解压炸弹
+	MaxPixels int
+	// Quality JPEG 缩放版本的编码质量
+	Quality  int
+	Variants []UploadVariant
+}
+
+// UploadConfigFromEnv 读取 ADMIN_TOKEN / UPLOAD_MAX_BYTES / UPLOAD_MAX_PIXELS
+func UploadConfigFromEnv(staticDir string) UploadConfig {
+	cfg := UploadConfig{
+		Dir:        staticDir,
+		AdminToken: os.Getenv("ADMIN_TOKEN"),
+		MaxBytes:   10 << 20,
+		MaxPixels:  40_000_000,
+		Quality:    85,
+		Variants:   []UploadVariant{{"thumbnail", 320}, {"medium", 1024}},
+	}
+	if n, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_BYTES"), 10, 64); err == nil && n > 0 {
+		cfg.MaxBytes = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("UPLOAD_MAX_PIXELS")); err == nil && n > 0 {
+		cfg.MaxPixels = n
+	}
+	return cfg
+}
+
+// Uploader 处理 POST /api/Upload：multipart 字段 file，需 Authorization: Bearer <ADMIN_TOKEN>
+type Uploader struct {
+	cfg UploadConfig
+}
+
+// NewUploader 创建上传处理器
+func NewUploader(cfg UploadConfig) *Uploader {
+	return &Uploader{cfg: cfg}
+}
+
+func (u *Uploader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	switch {
+	case r.Method == http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case r.Method != http.MethodPost:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	case u.cfg.AdminToken == "":
+		writeJSONError(w, http.StatusNotFound, "Not Found")
+		return
+	case !u.authorized(r):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	data, err := u.readFile(w, r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || errors.Is(err, errFileTooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "File too large")
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, status, err := u.store(data)
+	if err != nil {
+		log.Printf("\033[31m[Upload]\033[0m %v", err)
+		writeJSONError(w, status, err.Error())
+		return
+	}
+	log.Printf("\033[32m[Upload]\033[0m stored %s (%d bytes)", resp.Original.URL, resp.Original.Bytes)
+	writeJSONHeaders(w)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// authorized 常量时间比较 Bearer 令牌
+func (u *Uploader) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(u.cfg.AdminToken)) == 1
+}
+
+var errFileTooLarge = errors.New("file too large")
+
+// readFile 流式读取 multipart 中名为 file 的部分，超过 MaxBytes 即中止
+func (u *Uploader) readFile(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	// 为 multipart 边界与其它字段预留余量
+	r.Body = http.MaxBytesReader(w, r.Body, u.cfg.MaxBytes+64<<10)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("expected multipart/form-data")
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("missing file field")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, u.cfg.MaxBytes+1))
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > u.cfg.MaxBytes {
+			return nil, errFileTooLarge
+		}
+		return data, nil
+	}
+}
+
+// store 校验图片、清除位置信息、生成缩放版本并以内容哈希命名写入 Dir
+func (u *Uploader) store(data []byte) (*api.UploadResponse, int, error) {
+	format, err := images.Sniff(data)
+	if err != nil {
+		return nil, http.StatusUnsupportedMediaType, err
+	}
+	cfg, _, err := images.Config(data)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("invalid image data")
+	}
+	if cfg.Width*cfg.Height > u.cfg.MaxPixels {
+		return nil, http.StatusRequestEntityTooLarge, errors.New("image dimensions too large")
+	}
+	clean, err := images.StripLocation(data, format)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("invalid image data")
+	}
+	img, _, err := images.Decode(clean)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("invalid image data")
+	}
+
+	url, err := u.write(clean, images.Ext(format))
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	b := img.Bounds()
+	resp := &api.UploadResponse{
+		Original: api.ImageVariant{URL: url, Width: b.Dx(), Height: b.Dy(), Bytes: len(clean)},
+		Variants: make(map[string]api.ImageVariant, len(u.cfg.Variants)),
+		Markdown: "![](" + url + ")",
+	}
+
+	// 缩放版本重新编码，不含任何元数据；透明格式统一输出 PNG
+	outFormat := images.PNG
+	if format == images.JPEG {
+		outFormat = images.JPEG
+	}
+	for _, v := range u.cfg.Variants {
+		if b.Dx() <= v.Width {
+			resp.Variants[v.Name] = resp.Original
+			continue
+		}
+		resized := images.Resize(img, v.Width)
+		var buf bytes.Buffer
+		if err := images.Encode(&buf, resized, outFormat, u.cfg.Quality); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		url, err := u.write(buf.Bytes(), images.Ext(outFormat))
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		rb := resized.Bounds()
+		resp.Variants[v.Name] = api.ImageVariant{URL: url, Width: rb.Dx(), Height: rb.Dy(), Bytes: buf.Len()}
+	}
+	return resp, http.StatusCreated, nil
+}
+
+// write 以 img-<sha256 前 16 位>.<ext> 命名写入，同名文件已存在时直接复用；
+// 名称带内容指纹，静态服务会对其使用 immutable 缓存
+func (u *Uploader) write(data []byte, ext string) (string, error) {
+	sum := sha256.Sum256(data)
+	name := "img-" + hex.EncodeToString(sum[:8]) + ext
+	dst := filepath.Join(u.cfg.Dir, name)
+	if _, err := os.Stat(dst); err == nil {
+		return "/static/" + name, nil
+	}
+	tmp, err := os.CreateTemp(u.cfg.Dir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+	return "/static/" + name, nil
+}
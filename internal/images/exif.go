@@ -0,0 +1,104 @@
+package images
+
+import "encoding/binary"
+
+// EXIF 以 TIFF 结构存储：字节序标记、IFD0 偏移，每个 IFD 由 12 字节的条目组成
+const (
+	tagOrientation = 0x0112
+	tagGPSInfo     = 0x8825
+)
+
+// typeSizes TIFF 字段类型对应的单个值字节数
+var typeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// tiff 对 EXIF TIFF 数据的带边界检查的只读/就地修改视图
+type tiff struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+func parseTIFF(b []byte) (*tiff, bool) {
+	if len(b) < 8 {
+		return nil, false
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, false
+	}
+	if order.Uint16(b[2:]) != 42 {
+		return nil, false
+	}
+	return &tiff{b: b, order: order}, true
+}
+
+// entry 返回 IFD 中指定标签条目的起始位置
+func (t *tiff) entry(ifd uint32, tag uint16) (uint32, bool) {
+	if uint64(ifd)+2 > uint64(len(t.b)) {
+		return 0, false
+	}
+	n := uint32(t.order.Uint16(t.b[ifd:]))
+	for i := uint32(0); i < n; i++ {
+		e := ifd + 2 + 12*i
+		if uint64(e)+12 > uint64(len(t.b)) {
+			return 0, false
+		}
+		if t.order.Uint16(t.b[e:]) == tag {
+			return e, true
+		}
+	}
+	return 0, false
+}
+
+func (t *tiff) ifd0() uint32 {
+	return t.order.Uint32(t.b[4:])
+}
+
+// orientation 读取 IFD0 中的方向标签，缺失或非法时返回 1
+func (t *tiff) orientation() int {
+	e, ok := t.entry(t.ifd0(), tagOrientation)
+	if !ok || t.order.Uint16(t.b[e+2:]) != 3 {
+		return 1
+	}
+	o := int(t.order.Uint16(t.b[e+8:]))
+	if o < 1 || o > 8 {
+		return 1
+	}
+	return o
+}
+
+// scrubGPS 就地清零 GPS IFD 的全部条目及其外置数据，并将条目数置 0；
+// 数据长度不变，其余 EXIF（方向、相机参数等）保持有效
+func (t *tiff) scrubGPS() bool {
+	e, ok := t.entry(t.ifd0(), tagGPSInfo)
+	if !ok {
+		return false
+	}
+	gps := t.order.Uint32(t.b[e+8:])
+	if uint64(gps)+2 > uint64(len(t.b)) {
+		return false
+	}
+	n := uint32(t.order.Uint16(t.b[gps:]))
+	for i := uint32(0); i < n; i++ {
+		ent := gps + 2 + 12*i
+		if uint64(ent)+12 > uint64(len(t.b)) {
+			break
+		}
+		size := uint64(typeSizes[t.order.Uint16(t.b[ent+2:])]) * uint64(t.order.Uint32(t.b[ent+4:]))
+		if size > 4 {
+			off := uint64(t.order.Uint32(t.b[ent+8:]))
+			if off+size <= uint64(len(t.b)) {
+				clear(t.b[off : off+size])
+			}
+		}
+		clear(t.b[ent : ent+12])
+	}
+	t.order.PutUint16(t.b[gps:], 0)
+	return true
+}
@@ -0,0 +1,143 @@
+// Package images 提供上传与缩放所需的图片处理：格式嗅探、位置信息清除、方向校正与缩放编码
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // 注册 WebP 解码器
+)
+
+// 支持的图片格式，与 image.Decode 返回的格式名一致
+const (
+	JPEG = "jpeg"
+	PNG  = "png"
+	GIF  = "gif"
+	WebP = "webp"
+)
+
+// ErrUnsupported 不是受支持的图片格式
+var ErrUnsupported = errors.New("unsupported image format")
+
+// Sniff 按内容（而非文件名或客户端声明的类型）识别图片格式
+func Sniff(data []byte) (string, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return JPEG, nil
+	case "image/png":
+		return PNG, nil
+	case "image/gif":
+		return GIF, nil
+	case "image/webp":
+		return WebP, nil
+	}
+	return "", ErrUnsupported
+}
+
+// Ext 格式对应的文件扩展名
+func Ext(format string) string {
+	if format == JPEG {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// ContentType 格式对应的 MIME 类型
+func ContentType(format string) string {
+	return "image/" + format
+}
+
+// Config 只解析图片头部，返回格式与尺寸，用于在完整解码前拒绝超大图片
+func Config(data []byte) (image.Config, string, error) {
+	return image.DecodeConfig(bytes.NewReader(data))
+}
+
+// Decode 解码图片并按 EXIF 方向校正
+func Decode(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return orient(img, Orientation(data, format)), format, nil
+}
+
+// Resize 等比缩放到指定宽度；原图不宽于 width 时原样返回
+func Resize(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if width <= 0 || b.Dx() <= width {
+		return img
+	}
+	height := max(1, (b.Dy()*width+b.Dx()/2)/b.Dx())
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
+
+// Lossless 格式编码是否无损（quality 对其无效）。WebP 编码器只支持无损模式，
+// 照片转为 WebP 通常比 JPEG 大得多
+func Lossless(format string) bool {
+	return format != JPEG
+}
+
+// Encode 按格式编码；quality 仅对 JPEG 生效，其它格式忽略。WebP 为无损编码，GIF 输出单帧
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case JPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case PNG:
+		return png.Encode(w, img)
+	case GIF:
+		return gif.Encode(w, img, nil)
+	case WebP:
+		return nativewebp.Encode(w, img, nil)
+	}
+	return ErrUnsupported
+}
+
+// orient 按 EXIF 方向值（1~8）旋转/翻转图片
+func orient(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch o {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
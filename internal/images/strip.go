@@ -0,0 +1,236 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var (
+	exifHeader   = []byte("Exif\x00\x00")
+	xmpHeaders   = [][]byte{[]byte("http://ns.adobe.com/xap/1.0/\x00"), []byte("http://ns.adobe.com/xmp/extension/\x00")}
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+	// ErrMalformed 图片结构无法解析
+	ErrMalformed = errors.New("malformed image data")
+)
+
+// StripLocation 移除图片中的位置信息：清除 EXIF 中的 GPS 数据，并丢弃可能包含 GPS 的 XMP 元数据。
+// 像素数据与其余 EXIF（如方向）不变，返回新的切片
+func StripLocation(data []byte, format string) ([]byte, error) {
+	switch format {
+	case JPEG:
+		return stripJPEG(data)
+	case PNG:
+		return stripPNG(data)
+	case WebP:
+		return stripWebP(data)
+	default:
+		return bytes.Clone(data), nil
+	}
+}
+
+// Orientation 返回 EXIF 方向（1~8），没有 EXIF 时为 1
+func Orientation(data []byte, format string) int {
+	var exif []byte
+	switch format {
+	case JPEG:
+		walkJPEG(data, func(marker byte, payload []byte) bool {
+			if marker == 0xE1 && bytes.HasPrefix(payload, exifHeader) {
+				exif = payload[len(exifHeader):]
+				return false
+			}
+			return true
+		})
+	case PNG:
+		walkPNG(data, func(typ string, payload []byte) bool {
+			if typ == "eXIf" {
+				exif = payload
+				return false
+			}
+			return true
+		})
+	case WebP:
+		walkWebP(data, func(fourcc string, payload []byte) bool {
+			if fourcc == "EXIF" {
+				exif = bytes.TrimPrefix(payload, exifHeader)
+				return false
+			}
+			return true
+		})
+	}
+	if t, ok := parseTIFF(exif); ok {
+		return t.orientation()
+	}
+	return 1
+}
+
+// walkJPEG 依次回调 SOS 之前的各个带长度的段，fn 返回 false 时停止；返回 SOS 段的起始位置
+func walkJPEG(data []byte, fn func(marker byte, payload []byte) bool) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, ErrMalformed
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 0, ErrMalformed
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // 填充字节
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			return i, nil
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			i += 2
+			continue
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return 0, ErrMalformed
+		}
+		if !fn(marker, data[i+4:i+2+n]) {
+			return i, nil
+		}
+		i += 2 + n
+	}
+	return 0, ErrMalformed
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	sos, err := walkJPEG(data, func(marker byte, payload []byte) bool {
+		if marker == 0xE1 && isXMP(payload) {
+			return true
+		}
+		start := len(out)
+		out = append(out, 0xFF, marker, 0, 0)
+		binary.BigEndian.PutUint16(out[start+2:], uint16(len(payload)+2))
+		out = append(out, payload...)
+		if marker == 0xE1 && bytes.HasPrefix(payload, exifHeader) {
+			if t, ok := parseTIFF(out[start+4+len(exifHeader):]); ok {
+				t.scrubGPS()
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[sos:]...), nil
+}
+
+func isXMP(payload []byte) bool {
+	for _, h := range xmpHeaders {
+		if bytes.HasPrefix(payload, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkPNG 依次回调每个块，fn 返回 false 时停止
+func walkPNG(data []byte, fn func(typ string, payload []byte) bool) error {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return ErrMalformed
+	}
+	i := len(pngSignature)
+	for i+12 <= len(data) {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		if n < 0 || i+12+n > len(data) {
+			return ErrMalformed
+		}
+		typ := string(data[i+4 : i+8])
+		if !fn(typ, data[i+8:i+8+n]) || typ == "IEND" {
+			return nil
+		}
+		i += 12 + n
+	}
+	return ErrMalformed
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	out := append(make([]byte, 0, len(data)), pngSignature...)
+	err := walkPNG(data, func(typ string, payload []byte) bool {
+		if typ == "iTXt" && bytes.HasPrefix(payload, []byte("XML:com.adobe.xmp\x00")) {
+			return true
+		}
+		// ImageMagick 等工具会把原始 EXIF 存进文本块
+		if (typ == "tEXt" || typ == "zTXt") && bytes.HasPrefix(payload, []byte("Raw profile type")) {
+			return true
+		}
+		start := len(out)
+		out = binary.BigEndian.AppendUint32(out, uint32(len(payload)))
+		out = append(out, typ...)
+		out = append(out, payload...)
+		if typ == "eXIf" {
+			if t, ok := parseTIFF(out[start+8:]); ok {
+				t.scrubGPS()
+			}
+		}
+		out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[start+4:]))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// walkWebP 依次回调 RIFF 容器中的每个块，fn 返回 false 时停止
+func walkWebP(data []byte, fn func(fourcc string, payload []byte) bool) error {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return ErrMalformed
+	}
+	i := 12
+	for i+8 <= len(data) {
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if n < 0 || i+8+n > len(data) {
+			return ErrMalformed
+		}
+		if !fn(string(data[i:i+4]), data[i+8:i+8+n]) {
+			return nil
+		}
+		i += 8 + n + n&1
+	}
+	return nil
+}
+
+func stripWebP(data []byte) ([]byte, error) {
+	out := append(make([]byte, 0, len(data)), data[:12]...)
+	vp8x := -1
+	err := walkWebP(data, func(fourcc string, payload []byte) bool {
+		if fourcc == "XMP " {
+			return true
+		}
+		start := len(out)
+		out = append(out, fourcc...)
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(payload)))
+		out = append(out, payload...)
+		if len(payload)&1 == 1 {
+			out = append(out, 0)
+		}
+		switch fourcc {
+		case "VP8X":
+			vp8x = start
+		case "EXIF":
+			exif := out[start+8 : start+8+len(payload)]
+			if t, ok := parseTIFF(bytes.TrimPrefix(exif, exifHeader)); ok {
+				t.scrubGPS()
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if vp8x >= 0 && len(out) > vp8x+8 {
+		out[vp8x+8] &^= 0x04 // 清除 XMP 标志位
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
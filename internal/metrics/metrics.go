@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 指标统一注册在默认 Registry 上，/metrics 以 Prometheus 文本格式输出。
+// 标签取值保持低基数：route 使用路由模板而非原始 URL，operation/provider 为固定枚举。
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP 请求总数，按路由、方法与状态码区分",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP 请求耗时（秒），按路由、方法与状态码区分",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	mongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mongo_query_duration_seconds",
+		Help:    "MongoDB 查询耗时（秒），按操作区分",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	mongoErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_query_errors_total",
+		Help: "MongoDB 查询失败次数，按操作区分",
+	}, []string{"operation"})
+
+	mongoConnEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_connection_events_total",
+		Help: "MongoDB 连接事件次数（connect / disconnect）",
+	}, []string{"event"})
+
+	contentPathViolations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "content_path_violations_total",
+		Help: "因路径越出内容根目录而被拒绝的 markdown 读取次数",
+	})
+
+	contentWatchEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "content_watch_events_total",
+		Help: "内容目录监视到的 markdown 变更次数（created / modified / deleted）",
+	}, []string{"type"})
+
+	upstreamResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_requests_total",
+		Help: "外部服务调用结果次数（success / failure / retry），按提供商区分",
+	}, []string{"provider", "outcome"})
+)
+
+// 上游调用结果取值
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeRetry   = "retry"
+)
+
+// Mongo 连接事件取值
+const (
+	EventConnect    = "connect"
+	EventDisconnect = "disconnect"
+)
+
+// Handler 返回 /metrics 处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTP 记录一次 HTTP 请求
+func ObserveHTTP(route, method string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, code).Inc()
+	httpDuration.WithLabelValues(route, method, code).Observe(d.Seconds())
+}
+
+// ObserveMongo 记录一次 Mongo 操作耗时，err 非空时同时累加错误计数
+func ObserveMongo(operation string, d time.Duration, err error) {
+	mongoDuration.WithLabelValues(operation).Observe(d.Seconds())
+	if err != nil {
+		mongoErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// MongoConnEvent 记录连接建立或断开
+func MongoConnEvent(event string) {
+	mongoConnEvents.WithLabelValues(event).Inc()
+}
+
+// ContentWatchEvent 记录一次内容变更
+func ContentWatchEvent(eventType string) {
+	contentWatchEvents.WithLabelValues(eventType).Inc()
+}
+
+// UpstreamResult 记录外部提供商的一次调用结果
+func UpstreamResult(provider, outcome string) {
+	upstreamResults.WithLabelValues(provider, outcome).Inc()
+}
+
+// ContentPathViolation 记录一次越界的内容路径访问
+func ContentPathViolation() {
+	contentPathViolations.Inc()
+}
+
+// CacheStats 进程内缓存的统计快照
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64 // 不按字节计量的缓存为 0
+}
+
+// RegisterCache 注册一个缓存的统计指标，抓取时调用 stats 读取快照；
+// name 作为 cache 标签值，同名缓存只能注册一次
+func RegisterCache(name string, stats func() CacheStats) {
+	labels := prometheus.Labels{"cache": name}
+	counter := func(metric, help string, pick func(CacheStats) uint64) {
+		prometheus.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: metric, Help: help, ConstLabels: labels,
+		}, func() float64 { return float64(pick(stats())) }))
+	}
+	gauge := func(metric, help string, pick func(CacheStats) float64) {
+		prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: metric, Help: help, ConstLabels: labels,
+		}, func() float64 { return pick(stats()) }))
+	}
+	counter("cache_hits_total", "缓存命中次数", func(s CacheStats) uint64 { return s.Hits })
+	counter("cache_misses_total", "缓存未命中次数", func(s CacheStats) uint64 { return s.Misses })
+	counter("cache_evictions_total", "缓存淘汰次数", func(s CacheStats) uint64 { return s.Evictions })
+	gauge("cache_entries", "缓存条目数", func(s CacheStats) float64 { return float64(s.Entries) })
+	gauge("cache_bytes", "缓存占用字节数", func(s CacheStats) float64 { return float64(s.Bytes) })
+}
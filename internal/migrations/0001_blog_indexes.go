@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// 0001：博客集合基础索引
+//   - ID 唯一：详情查询按 ID 命中，同时防止重复编号
+//   - Date 降序：最新博客查询与列表排序
+//   - Slug 唯一（仅对存在 Slug 的文档生效）
+//   - Title/Summary 全文索引
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "blog_indexes",
+		Up: func(ctx context.Context, t Target) error {
+			models := []mongo.IndexModel{
+				{Keys: bson.D{{Key: "ID", Value: 1}}, Options: indexOpts("uniq_id").SetUnique(true)},
+				{Keys: bson.D{{Key: "Date", Value: -1}}, Options: indexOpts("date_desc")},
+				{
+					Keys: bson.D{{Key: "Slug", Value: 1}},
+					Options: indexOpts("uniq_slug").SetUnique(true).
+						SetPartialFilterExpression(bson.D{{Key: "Slug", Value: bson.D{{Key: "$type", Value: "string"}}}}),
+				},
+				{
+					Keys:    bson.D{{Key: "Title", Value: "text"}, {Key: "Summary", Value: "text"}},
+					Options: indexOpts("text_title_summary"),
+				},
+			}
+			if _, err := t.Blogs.Indexes().CreateMany(ctx, models); err != nil {
+				return fmt.Errorf("failed to create indexes: %v", err)
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, t Target) error {
+			return dropIndexes(ctx, t.Blogs, "uniq_id", "date_desc", "uniq_slug", "text_title_summary")
+		},
+	})
+}
@@ -0,0 +1,143 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// 0002：将字符串 Date 转换为 BSON datetime
+//   - Date:      按 BLOG_TIMEZONE 解析不带偏移量的历史格式
+//   - DateTZ:    记录解析所用时区，输出 RFC 3339 时还原偏移量
+//   - DateRaw:   保留原始字符串，供 Down 原样还原
+//   - UpdatedAt: 缺失时以 Date 回填，并以 UpdatedAtBackfilled 标记，Down 只删除回填的值
+//
+// 存在无法解析的日期时整体报错并列出 ID，修正后可重新执行（已转换的文档会被跳过）。
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "typed_dates",
+		Up:      typedDatesUp,
+		Down:    typedDatesDown,
+	})
+}
+
+func typedDatesUp(ctx context.Context, t Target) error {
+	loc := utils.BlogTimezone()
+	filter := bson.D{{Key: "Date", Value: bson.D{{Key: "$type", Value: "string"}}}}
+	cursor, err := t.Blogs.Find(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to find string dates: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var (
+		models []mongo.WriteModel
+		bad    []string
+	)
+	for cursor.Next(ctx) {
+		var doc struct {
+			OID       any        `bson:"_id"`
+			ID        int        `bson:"ID"`
+			Date      string     `bson:"Date"`
+			UpdatedAt *time.Time `bson:"UpdatedAt"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode blog: %v", err)
+		}
+		parsed, err := utils.ParseBlogDate(doc.Date, loc)
+		if err != nil {
+			bad = append(bad, fmt.Sprintf("ID=%d Date=%q", doc.ID, doc.Date))
+			continue
+		}
+		set := bson.D{
+			{Key: "Date", Value: parsed},
+			{Key: "DateTZ", Value: loc.String()},
+			{Key: "DateRaw", Value: doc.Date},
+		}
+		if doc.UpdatedAt == nil {
+			set = append(set,
+				bson.E{Key: "UpdatedAt", Value: parsed},
+				bson.E{Key: "UpdatedAtBackfilled", Value: true})
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: doc.OID}}).
+			SetUpdate(bson.D{{Key: "$set", Value: set}}))
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %v", err)
+	}
+
+	if len(models) > 0 {
+		if _, err := t.Blogs.BulkWrite(ctx, models); err != nil {
+			return fmt.Errorf("failed to convert dates: %v", err)
+		}
+	}
+	log.Printf("[Migrate] typed_dates converted %d document(s)", len(models))
+	if len(bad) > 0 {
+		return fmt.Errorf("unparseable dates: %s", strings.Join(bad, "; "))
+	}
+	return nil
+}
+
+func typedDatesDown(ctx context.Context, t Target) error {
+	filter := bson.D{{Key: "Date", Value: bson.D{{Key: "$type", Value: "date"}}}}
+	cursor, err := t.Blogs.Find(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to find typed dates: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var models []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc struct {
+			OID     any       `bson:"_id"`
+			Date    time.Time `bson:"Date"`
+			DateTZ  string    `bson:"DateTZ"`
+			DateRaw string    `bson:"DateRaw"`
+			// Backfilled 为 true 表示 UpdatedAt 由 Up 回填；迁移前已有的 UpdatedAt 保持不变
+			Backfilled bool `bson:"UpdatedAtBackfilled"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode blog: %v", err)
+		}
+		raw := doc.DateRaw
+		if raw == "" {
+			loc := utils.BlogTimezone()
+			if l, err := time.LoadLocation(doc.DateTZ); err == nil && doc.DateTZ != "" {
+				loc = l
+			}
+			raw = doc.Date.In(loc).Format("2006-01-02 15:04:05")
+		}
+		unset := bson.D{
+			{Key: "DateTZ", Value: ""},
+			{Key: "DateRaw", Value: ""},
+			{Key: "UpdatedAtBackfilled", Value: ""},
+		}
+		if doc.Backfilled {
+			unset = append(unset, bson.E{Key: "UpdatedAt", Value: ""})
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: doc.OID}}).
+			SetUpdate(bson.D{
+				{Key: "$set", Value: bson.D{{Key: "Date", Value: raw}}},
+				{Key: "$unset", Value: unset},
+			}))
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %v", err)
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	if _, err := t.Blogs.BulkWrite(ctx, models); err != nil {
+		return fmt.Errorf("failed to restore string dates: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CollectionName 记录已执行迁移的集合
+const CollectionName = "migrations"
+
+// Target 迁移作用的数据库与博客集合
+type Target struct {
+	DB    *mongo.Database
+	Blogs *mongo.Collection
+}
+
+// Migration 一个带版本号的迁移；Up/Down 需可重复执行（幂等），
+// 以便在中途失败后重新运行
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, t Target) error
+	Down    func(ctx context.Context, t Target) error
+}
+
+// record 迁移集合中的一条记录
+type record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+// Status 单个迁移的执行状态
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// registry 全部迁移，按版本号升序；新增迁移只需追加到此列表
+var registry []Migration
+
+func register(m Migration) {
+	registry = append(registry, m)
+	sort.Slice(registry, func(i, j int) bool { return registry[i].Version < registry[j].Version })
+}
+
+// Runner 执行迁移并将结果记录到 migrations 集合
+type Runner struct {
+	target     Target
+	records    *mongo.Collection
+	migrations []Migration
+}
+
+// NewRunner 基于博客集合创建 Runner，migrations 集合与其位于同一数据库
+func NewRunner(blogs *mongo.Collection) *Runner {
+	db := blogs.Database()
+	return &Runner{
+		target:     Target{DB: db, Blogs: blogs},
+		records:    db.Collection(CollectionName),
+		migrations: registry,
+	}
+}
+
+// applied 读取已执行的迁移，按版本号索引
+func (r *Runner) applied(ctx context.Context) (map[int]record, error) {
+	cursor, err := r.records.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	done := make(map[int]record)
+	for cursor.Next(ctx) {
+		var rec record
+		if err := cursor.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("failed to decode migration record: %v", err)
+		}
+		done[rec.Version] = rec
+	}
+	return done, cursor.Err()
+}
+
+// Status 返回全部迁移及其执行状态
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	done, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		rec, ok := done[m.Version]
+		out = append(out, Status{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: rec.AppliedAt})
+	}
+	return out, nil
+}
+
+// Up 依次执行未应用的迁移，直到版本 to（to<=0 表示全部）。返回本次执行的数量
+func (r *Runner) Up(ctx context.Context, to int) (int, error) {
+	done, err := r.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, m := range r.migrations {
+		if to > 0 && m.Version > to {
+			break
+		}
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		log.Printf("[Migrate] up %d_%s", m.Version, m.Name)
+		if err := m.Up(ctx, r.target); err != nil {
+			return n, fmt.Errorf("migration %d_%s up failed: %v", m.Version, m.Name, err)
+		}
+		rec := record{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}
+		if _, err := r.records.InsertOne(ctx, rec); err != nil {
+			return n, fmt.Errorf("failed to record migration %d: %v", m.Version, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Down 按版本号倒序回滚最近 steps 个已执行的迁移。返回本次回滚的数量
+func (r *Runner) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, errors.New("steps must be positive")
+	}
+	done, err := r.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := len(r.migrations) - 1; i >= 0 && n < steps; i-- {
+		m := r.migrations[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return n, fmt.Errorf("migration %d_%s is irreversible", m.Version, m.Name)
+		}
+		log.Printf("[Migrate] down %d_%s", m.Version, m.Name)
+		if err := m.Down(ctx, r.target); err != nil {
+			return n, fmt.Errorf("migration %d_%s down failed: %v", m.Version, m.Name, err)
+		}
+		if _, err := r.records.DeleteOne(ctx, bson.D{{Key: "_id", Value: m.Version}}); err != nil {
+			return n, fmt.Errorf("failed to remove migration record %d: %v", m.Version, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// dropIndexes 删除指定名称的索引，索引不存在时忽略
+func dropIndexes(ctx context.Context, coll *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		if _, err := coll.Indexes().DropOne(ctx, name); err != nil {
+			var cmdErr mongo.CommandError
+			if errors.As(err, &cmdErr) && cmdErr.Code == 27 { // IndexNotFound
+				continue
+			}
+			return fmt.Errorf("failed to drop index %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// indexOpts 以指定名称创建索引选项，名称固定便于 Down 精确删除
+func indexOpts(name string) *options.IndexOptions {
+	return options.Index().SetName(name)
+}
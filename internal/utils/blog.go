@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/api"
+)
+
+// BlogStore 博客存储的统一接口，由 MongoManager 与 BoltStore 实现，
+// 通过 STORAGE_BACKEND 选择
+type BlogStore interface {
+	// GetBlogInfo 获取所有博客的标题、概述与日期
+	GetBlogInfo(ctx context.Context) ([]api.BlogResponse, error)
+	// GetLatestBlog 按发布时间获取最新一篇博客
+	GetLatestBlog(ctx context.Context) (api.BlogResponse, error)
+	// GetBlogContentByID 根据 ID 获取博客 markdown 内容，不存在时返回 ID=404 的占位内容
+	GetBlogContentByID(ctx context.Context, id int) (api.BlogContent, error)
+	// GetBlogPath 获取单篇博客的 markdown 路径与修改时间，不存在时返回 ErrBlogNotFound
+	GetBlogPath(ctx context.Context, id int) (BlogPath, error)
+	// ListBlogPaths 列出全部博客的 ID、标题与 markdown 路径，供维护任务使用
+	ListBlogPaths(ctx context.Context) ([]BlogPath, error)
+	// Close 释放底层连接或文件
+	Close(ctx context.Context) error
+}
+
+// BlogWriter 可写的博客存储，供内容热加载使用；MongoManager 与 BoltStore 均实现
+type BlogWriter interface {
+	// SaveBlog 按 ID 新建或更新博客；零值字段（空字符串、零时间、nil）保持原值不变
+	SaveBlog(ctx context.Context, doc BlogDocument) error
+	// DeleteBlog 按 ID 删除博客，不存在时不报错
+	DeleteBlog(ctx context.Context, id int) error
+}
+
+// BlogDocument 写入存储的博客字段
+type BlogDocument struct {
+	ID        int
+	Title     string
+	Summary   string
+	Slug      string
+	Date      time.Time
+	DateTZ    string
+	UpdatedAt *time.Time
+	Path      string
+}
+
+// BlogDocumentSource 能导出全部博客完整字段的存储，用于在存储之间复制
+type BlogDocumentSource interface {
+	// BlogDocuments 按 ID 升序返回全部博客
+	BlogDocuments(ctx context.Context) ([]BlogDocument, error)
+}
+
+// ErrBlogNotFound 指定 ID 的博客不存在
+var ErrBlogNotFound = errors.New("blog not found")
+
+// BlogPath 博客与其 markdown 路径
+type BlogPath struct {
+	ID        int
+	Title     string
+	Path      string
+	UpdatedAt *time.Time
+}
+
+// blogDoc 博客文档在存储中的结构
+//
+//	Date:      发布时间（BSON datetime，兼容迁移前的字符串）
+//	DateTZ:    发布时间所属时区（IANA 名称），输出 RFC 3339 时使用
+//	UpdatedAt: 最后修改时间，可为空
+//	Path:      markdown 文件路径
+type blogDoc struct {
+	ID        int        `bson:"ID" json:"id"`
+	Title     string     `bson:"Title" json:"title"`
+	Summary   string     `bson:"Summary" json:"summary"`
+	Slug      string     `bson:"Slug,omitempty" json:"slug,omitempty"`
+	Date      blogDate   `bson:"Date" json:"date"`
+	DateTZ    string     `bson:"DateTZ,omitempty" json:"dateTZ,omitempty"`
+	UpdatedAt *time.Time `bson:"UpdatedAt,omitempty" json:"updatedAt,omitempty"`
+	Path      string     `bson:"Path,omitempty" json:"path,omitempty"`
+}
+
+// merge 将 BlogDocument 中的非零字段覆盖到 d
+func (d *blogDoc) merge(in BlogDocument) {
+	d.ID = in.ID
+	if in.Title != "" {
+		d.Title = in.Title
+	}
+	if in.Summary != "" {
+		d.Summary = in.Summary
+	}
+	if in.Slug != "" {
+		d.Slug = in.Slug
+	}
+	if !in.Date.IsZero() {
+		d.Date = blogDate{in.Date}
+	}
+	if in.DateTZ != "" {
+		d.DateTZ = in.DateTZ
+	}
+	if in.UpdatedAt != nil {
+		d.UpdatedAt = in.UpdatedAt
+	}
+	if in.Path != "" {
+		d.Path = in.Path
+	}
+}
+
+// document 转换为存储无关的 BlogDocument
+func (d blogDoc) document() BlogDocument {
+	return BlogDocument{
+		ID:        d.ID,
+		Title:     d.Title,
+		Summary:   d.Summary,
+		Slug:      d.Slug,
+		Date:      d.Date.Time,
+		DateTZ:    d.DateTZ,
+		UpdatedAt: d.UpdatedAt,
+		Path:      d.Path,
+	}
+}
+
+// blogPath 提取路径相关字段
+func (d blogDoc) blogPath() BlogPath {
+	return BlogPath{ID: d.ID, Title: d.Title, Path: d.Path, UpdatedAt: d.UpdatedAt}
+}
+
+// response 转换为接口返回结构，时间转换到文档时区
+func (d blogDoc) response() api.BlogResponse {
+	resp := api.BlogResponse{
+		ID:      d.ID,
+		Title:   d.Title,
+		Summary: d.Summary,
+		Date:    inZone(d.Date.Time, d.DateTZ),
+	}
+	if d.UpdatedAt != nil && !d.UpdatedAt.IsZero() {
+		u := inZone(*d.UpdatedAt, d.DateTZ)
+		resp.UpdatedAt = &u
+	}
+	return resp
+}
+
+// notFoundContent 博客不存在时的占位内容（沿用原有接口约定）
+func notFoundContent() api.BlogContent {
+	return api.BlogContent{
+		ID:   404,
+		Text: "博客不存在",
+	}
+}
+
+// readBlogContent 在内容根目录内读取 markdown 文件，越界或读取失败均视为博客不存在
+func readBlogContent(root *ContentRoot, id int, path string) api.BlogContent {
+	if root == nil {
+		return notFoundContent()
+	}
+	real, err := root.Resolve(path)
+	if errors.Is(err, ErrPathEscapesRoot) {
+		reportEscape(id, path, root)
+		return notFoundContent()
+	}
+	if err != nil {
+		return notFoundContent()
+	}
+	st, err := os.Stat(real)
+	if err != nil {
+		return notFoundContent()
+	}
+	content, err := os.ReadFile(real)
+	if err != nil {
+		return notFoundContent()
+	}
+	return api.BlogContent{
+		ID:      id,
+		Text:    string(content),
+		ModTime: st.ModTime(),
+	}
+}
+
+// PathViolation 审计发现的问题文档
+type PathViolation struct {
+	BlogPath
+	Reason string
+}
+
+// AuditContentPaths 检查全部文档的 Path：越出内容根目录或文件缺失的文档都会列出
+func AuditContentPaths(ctx context.Context, store BlogStore, root *ContentRoot) ([]PathViolation, error) {
+	paths, err := store.ListBlogPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []PathViolation
+	for _, p := range paths {
+		_, err := root.Resolve(p.Path)
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, ErrPathEscapesRoot):
+			out = append(out, PathViolation{BlogPath: p, Reason: "escapes content root"})
+		case errors.Is(err, os.ErrNotExist):
+			out = append(out, PathViolation{BlogPath: p, Reason: "file not found"})
+		default:
+			out = append(out, PathViolation{BlogPath: p, Reason: err.Error()})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/api"
+	"github.com/LtePrince/Personal-Website-backend/internal/tracing"
+	"go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// bbolt 单文件存储：适合没有 MongoDB 的环境（如 staging）。
+// 数据布局：blogs 桶中 key 为大端序 ID，value 为 blogDoc 的 JSON。
+var boltBlogsBucket = []byte("blogs")
+
+// ErrNegativeBlogID 负数 ID 的大端序 key 会排在所有正数之后，破坏按 ID 排序，因此拒绝
+var ErrNegativeBlogID = errors.New("blog ID must not be negative")
+
+// BoltStore 基于 bbolt 的 BlogStore 实现，可并发使用
+type BoltStore struct {
+	db   *bbolt.DB
+	root *ContentRoot
+}
+
+// OpenBoltStore 打开（必要时创建）数据库文件；root 含义同 NewMongoManager
+func OpenBoltStore(path string, root *ContentRoot) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt db %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBlogsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to init bolt db: %v", err)
+	}
+	return &BoltStore{db: db, root: root}, nil
+}
+
+func boltKey(id int) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(id))
+	return k
+}
+
+// Close 关闭数据库文件
+func (b *BoltStore) Close(context.Context) error {
+	return b.db.Close()
+}
+
+// forEach 按 ID 升序遍历全部文档
+func (b *BoltStore) forEach(fn func(doc blogDoc) error) error {
+	return b.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBlogsBucket).ForEach(func(_, v []byte) error {
+			var doc blogDoc
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return fmt.Errorf("failed to decode blog: %v", err)
+			}
+			return fn(doc)
+		})
+	})
+}
+
+// GetBlogInfo 获取所有博客的标题、概述与日期
+func (b *BoltStore) GetBlogInfo(ctx context.Context) ([]api.BlogResponse, error) {
+	_, span := tracing.Start(ctx, "bolt.GetBlogInfo")
+	var blogs []api.BlogResponse
+	err := b.forEach(func(doc blogDoc) error {
+		blogs = append(blogs, doc.response())
+		return nil
+	})
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return blogs, nil
+}
+
+// GetLatestBlog 按发布时间获取最新一篇博客，同一时间取 ID 较大者
+func (b *BoltStore) GetLatestBlog(ctx context.Context) (api.BlogResponse, error) {
+	_, span := tracing.Start(ctx, "bolt.GetLatestBlog")
+	var (
+		latest blogDoc
+		found  bool
+	)
+	err := b.forEach(func(doc blogDoc) error {
+		if !found || doc.Date.After(latest.Date.Time) || (doc.Date.Equal(latest.Date.Time) && doc.ID > latest.ID) {
+			latest, found = doc, true
+		}
+		return nil
+	})
+	if err == nil && !found {
+		err = errors.New("no blogs")
+	}
+	tracing.End(span, err)
+	if err != nil {
+		return api.BlogResponse{}, fmt.Errorf("failed to find latest blog: %v", err)
+	}
+	return latest.response(), nil
+}
+
+// GetBlogContentByID 根据 ID 获取博客内容
+func (b *BoltStore) GetBlogContentByID(ctx context.Context, id int) (api.BlogContent, error) {
+	_, span := tracing.Start(ctx, "bolt.GetBlogContentByID", attribute.Int("blog.id", id))
+	doc, found, err := b.get(id)
+	tracing.End(span, err)
+	if err != nil {
+		return api.BlogContent{}, err
+	}
+	if !found {
+		return notFoundContent(), nil
+	}
+	return readBlogContent(b.root, doc.ID, doc.Path), nil
+}
+
+// ListBlogPaths 列出全部博客的 ID、标题与 markdown 路径
+func (b *BoltStore) ListBlogPaths(context.Context) ([]BlogPath, error) {
+	var out []BlogPath
+	err := b.forEach(func(doc blogDoc) error {
+		out = append(out, doc.blogPath())
+		return nil
+	})
+	return out, err
+}
+
+// BlogDocuments 按 ID 升序返回全部博客的完整字段
+func (b *BoltStore) BlogDocuments(context.Context) ([]BlogDocument, error) {
+	var out []BlogDocument
+	err := b.forEach(func(doc blogDoc) error {
+		out = append(out, doc.document())
+		return nil
+	})
+	return out, err
+}
+
+// GetBlogPath 获取单篇博客的 markdown 路径与修改时间
+func (b *BoltStore) GetBlogPath(_ context.Context, id int) (BlogPath, error) {
+	doc, found, err := b.get(id)
+	if err != nil {
+		return BlogPath{}, err
+	}
+	if !found {
+		return BlogPath{}, ErrBlogNotFound
+	}
+	return doc.blogPath(), nil
+}
+
+// get 按 ID 读取单个文档
+func (b *BoltStore) get(id int) (doc blogDoc, found bool, err error) {
+	err = b.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(boltBlogsBucket).Get(boltKey(id))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &doc)
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to read blog %d: %v", id, err)
+	}
+	return doc, found, err
+}
+
+// SaveBlog 按 ID 新建或更新博客，只覆盖非零字段
+func (b *BoltStore) SaveBlog(_ context.Context, in BlogDocument) error {
+	if in.ID < 0 {
+		return fmt.Errorf("failed to save blog %d: %w", in.ID, ErrNegativeBlogID)
+	}
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(boltBlogsBucket)
+		var doc blogDoc
+		if v := bucket.Get(boltKey(in.ID)); v != nil {
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return fmt.Errorf("failed to decode blog %d: %v", in.ID, err)
+			}
+		}
+		doc.merge(in)
+		v, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("failed to encode blog %d: %v", in.ID, err)
+		}
+		return bucket.Put(boltKey(in.ID), v)
+	})
+}
+
+// DeleteBlog 按 ID 删除博客
+func (b *BoltStore) DeleteBlog(_ context.Context, id int) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBlogsBucket).Delete(boltKey(id))
+	})
+}
+
+// replaceAll 以 docs 整体替换桶内数据
+func (b *BoltStore) replaceAll(docs []blogDoc) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.DeleteBucket(boltBlogsBucket); err != nil && !errors.Is(err, bbolt.ErrBucketNotFound) {
+			return err
+		}
+		bucket, err := tx.CreateBucket(boltBlogsBucket)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			v, err := json.Marshal(doc)
+			if err != nil {
+				return fmt.Errorf("failed to encode blog %d: %v", doc.ID, err)
+			}
+			if err := bucket.Put(boltKey(doc.ID), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CopyToBolt 将 src 中的博客完整复制到 bbolt 文件（覆盖原有数据），返回复制的文档数。
+// 有重复或负数 ID 时整体拒绝，目标文件保持不变
+func CopyToBolt(ctx context.Context, src BlogDocumentSource, b *BoltStore) (int, error) {
+	in, err := src.BlogDocuments(ctx)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[int]bool, len(in))
+	docs := make([]blogDoc, len(in))
+	for i, d := range in {
+		if seen[d.ID] {
+			return 0, fmt.Errorf("duplicate blog ID %d in source", d.ID)
+		}
+		if d.ID < 0 {
+			return 0, fmt.Errorf("blog %d in source: %w", d.ID, ErrNegativeBlogID)
+		}
+		seen[d.ID] = true
+		docs[i].merge(d)
+	}
+	if err := b.replaceAll(docs); err != nil {
+		return 0, err
+	}
+	log.Printf("[Bolt] copied %d blog(s)", len(docs))
+	return len(docs), nil
+}
@@ -0,0 +1,403 @@
+package utils
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/api"
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// ContentCacheConfig 博客正文缓存配置
+type ContentCacheConfig struct {
+	// MaxBytes 缓存正文与元数据的总字节上限，超出时按最近最少使用淘汰；<=0 关闭缓存
+	MaxBytes int64
+	// MetaTTL 元数据（路径、UpdatedAt、列表与最新一篇）重新向存储确认的间隔；
+	// 文件本身每次命中都会 stat 校验，内容监视器写入时立即失效
+	MetaTTL time.Duration
+}
+
+// ContentCacheConfigFromEnv 读取 CONTENT_CACHE_MAX_BYTES / CONTENT_CACHE_META_TTL
+func ContentCacheConfigFromEnv() ContentCacheConfig {
+	return ContentCacheConfig{
+		MaxBytes: int64(envInt("CONTENT_CACHE_MAX_BYTES", 32<<20)),
+		MetaTTL:  envDuration("CONTENT_CACHE_META_TTL", "30s"),
+	}
+}
+
+// contentEntry 一篇博客的缓存条目
+type contentEntry struct {
+	meta    BlogPath
+	real    string // 解析后的真实文件路径
+	body    string
+	modTime time.Time
+	size    int64
+	// checkedAt 最近一次向存储确认元数据的时间（UnixNano）；命中时无锁读写
+	checkedAt atomic.Int64
+}
+
+// cost 条目占用的近似字节数
+func (e *contentEntry) cost() int64 {
+	return int64(len(e.body)+len(e.real)+len(e.meta.Path)+len(e.meta.Title)) + 128
+}
+
+// blogResponseCost 列表项占用的近似字节数
+func blogResponseCost(b api.BlogResponse) int64 {
+	return int64(len(b.Title)+len(b.Summary)) + 96
+}
+
+// ContentCache 为 BlogStore 增加有界 LRU 正文缓存：命中时校验文件 mtime/size，
+// 并按 MetaTTL 向存储确认文档未更新。博客列表与最新一篇也缓存 MetaTTL，
+// 计入同一字节上限，Invalidate 时一并清除；其余方法直接委托给底层存储
+type ContentCache struct {
+	BlogStore
+	root *ContentRoot
+	cfg  ContentCacheConfig
+
+	mu    sync.Mutex
+	ll    *list.List // 前端为最近使用
+	items map[int]*list.Element
+	bytes int64 // 正文条目与列表元数据合计
+
+	// 列表与最新一篇；fetchedAt 为零表示未缓存。metaGen 在失效时递增，
+	// 失效前发起的加载完成后不会写回旧数据
+	list      []api.BlogResponse
+	listAt    time.Time
+	latest    api.BlogResponse
+	latestAt  time.Time
+	metaBytes int64
+	metaGen   uint64
+
+	hits, misses, evictions atomic.Uint64
+
+	group singleflight.Group
+}
+
+// NewContentCache 包装 store；root 为 nil 时正文一律返回不存在
+func NewContentCache(store BlogStore, root *ContentRoot, cfg ContentCacheConfig) *ContentCache {
+	return &ContentCache{
+		BlogStore: store,
+		root:      root,
+		cfg:       cfg,
+		ll:        list.New(),
+		items:     make(map[int]*list.Element),
+	}
+}
+
+// GetBlogContentByID 优先从缓存返回博客正文
+func (c *ContentCache) GetBlogContentByID(ctx context.Context, id int) (api.BlogContent, error) {
+	if c.cfg.MaxBytes <= 0 {
+		return c.BlogStore.GetBlogContentByID(ctx, id)
+	}
+	if e := c.lookup(ctx, id); e != nil {
+		c.hits.Add(1)
+		return api.BlogContent{ID: e.meta.ID, Text: e.body, ModTime: e.modTime}, nil
+	}
+	c.misses.Add(1)
+
+	v, err := c.shared(ctx, strconv.Itoa(id), func(ctx context.Context) (any, error) {
+		return c.load(ctx, id)
+	})
+	if err != nil {
+		return api.BlogContent{}, err
+	}
+	return v.(api.BlogContent), nil
+}
+
+// GetBlogInfo 在 MetaTTL 内返回缓存的博客列表
+func (c *ContentCache) GetBlogInfo(ctx context.Context) ([]api.BlogResponse, error) {
+	if c.cfg.MaxBytes <= 0 {
+		return c.BlogStore.GetBlogInfo(ctx)
+	}
+	c.mu.Lock()
+	list, ok := c.list, !c.listAt.IsZero() && time.Since(c.listAt) < c.cfg.MetaTTL
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		return slices.Clone(list), nil
+	}
+	c.misses.Add(1)
+
+	v, err := c.shared(ctx, "list", func(ctx context.Context) (any, error) {
+		gen := c.generation()
+		list, err := c.BlogStore.GetBlogInfo(ctx)
+		if err == nil {
+			c.putList(gen, list)
+		}
+		return list, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 调用方可能修改返回的切片，不能与缓存共用底层数组
+	return slices.Clone(v.([]api.BlogResponse)), nil
+}
+
+// GetLatestBlog 在 MetaTTL 内返回缓存的最新一篇博客
+func (c *ContentCache) GetLatestBlog(ctx context.Context) (api.BlogResponse, error) {
+	if c.cfg.MaxBytes <= 0 {
+		return c.BlogStore.GetLatestBlog(ctx)
+	}
+	c.mu.Lock()
+	latest, ok := c.latest, !c.latestAt.IsZero() && time.Since(c.latestAt) < c.cfg.MetaTTL
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		return latest, nil
+	}
+	c.misses.Add(1)
+
+	v, err := c.shared(ctx, "latest", func(ctx context.Context) (any, error) {
+		gen := c.generation()
+		latest, err := c.BlogStore.GetLatestBlog(ctx)
+		if err == nil {
+			c.putLatest(gen, latest)
+		}
+		return latest, err
+	})
+	if err != nil {
+		return api.BlogResponse{}, err
+	}
+	return v.(api.BlogResponse), nil
+}
+
+// shared 合并同一 key 的并发加载。合并后的加载不随单个调用方取消，
+// 避免一个断开的请求让其它等待者一起失败；每个调用方仍按自己的 ctx 放弃等待
+func (c *ContentCache) shared(ctx context.Context, key string, fn func(context.Context) (any, error)) (any, error) {
+	ch := c.group.DoChan(key, func() (any, error) {
+		return fn(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		return res.Val, res.Err
+	}
+}
+
+func (c *ContentCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metaGen
+}
+
+// putList 缓存列表；加载期间发生过失效或超出上限时不缓存
+func (c *ContentCache) putList(gen uint64, list []api.BlogResponse) {
+	var cost int64
+	for _, b := range list {
+		cost += blogResponseCost(b)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.metaGen {
+		return
+	}
+	var old int64
+	for _, b := range c.list {
+		old += blogResponseCost(b)
+	}
+	if cost > c.cfg.MaxBytes {
+		c.list, c.listAt = nil, time.Time{}
+		c.setMetaBytesLocked(c.metaBytes - old)
+		return
+	}
+	c.list, c.listAt = slices.Clone(list), time.Now()
+	c.setMetaBytesLocked(c.metaBytes - old + cost)
+}
+
+// putLatest 缓存最新一篇；加载期间发生过失效时不缓存
+func (c *ContentCache) putLatest(gen uint64, latest api.BlogResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.metaGen {
+		return
+	}
+	if c.latestAt.IsZero() {
+		c.setMetaBytesLocked(c.metaBytes + blogResponseCost(latest))
+	} else {
+		c.setMetaBytesLocked(c.metaBytes - blogResponseCost(c.latest) + blogResponseCost(latest))
+	}
+	c.latest, c.latestAt = latest, time.Now()
+}
+
+// setMetaBytesLocked 更新元数据占用，并淘汰正文条目直到回到上限以内
+func (c *ContentCache) setMetaBytesLocked(n int64) {
+	c.bytes += n - c.metaBytes
+	c.metaBytes = n
+	c.trimLocked()
+}
+
+// invalidateMetaLocked 清除列表与最新一篇，并让进行中的加载作废
+func (c *ContentCache) invalidateMetaLocked() {
+	c.metaGen++
+	c.bytes -= c.metaBytes
+	c.metaBytes = 0
+	c.list, c.listAt = nil, time.Time{}
+	c.latest, c.latestAt = api.BlogResponse{}, time.Time{}
+}
+
+// lookup 返回仍然有效的缓存条目；失效条目会被移除
+func (c *ContentCache) lookup(ctx context.Context, id int) *contentEntry {
+	c.mu.Lock()
+	el, ok := c.items[id]
+	if !ok {
+		c.mu.Unlock()
+		return nil
+	}
+	e := el.Value.(*contentEntry)
+	c.mu.Unlock()
+
+	if !c.fresh(ctx, e) {
+		c.Invalidate(id)
+		return nil
+	}
+
+	c.mu.Lock()
+	if el, ok := c.items[id]; ok && el.Value == e {
+		c.ll.MoveToFront(el)
+	}
+	c.mu.Unlock()
+	return e
+}
+
+// fresh 检查文件 mtime/size，以及（超过 MetaTTL 时）存储中的路径与 UpdatedAt
+func (c *ContentCache) fresh(ctx context.Context, e *contentEntry) bool {
+	st, err := os.Stat(e.real)
+	if err != nil || !st.ModTime().Equal(e.modTime) || st.Size() != e.size {
+		return false
+	}
+	if time.Since(time.Unix(0, e.checkedAt.Load())) < c.cfg.MetaTTL {
+		return true
+	}
+	meta, err := c.BlogStore.GetBlogPath(ctx, e.meta.ID)
+	if err != nil || meta.Path != e.meta.Path || !sameTime(meta.UpdatedAt, e.meta.UpdatedAt) {
+		return false
+	}
+	e.checkedAt.Store(time.Now().UnixNano())
+	return true
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// load 从存储与磁盘读取正文并放入缓存
+func (c *ContentCache) load(ctx context.Context, id int) (api.BlogContent, error) {
+	meta, err := c.BlogStore.GetBlogPath(ctx, id)
+	if errors.Is(err, ErrBlogNotFound) {
+		return notFoundContent(), nil
+	}
+	if err != nil {
+		return api.BlogContent{}, err
+	}
+	if c.root == nil {
+		return notFoundContent(), nil
+	}
+	real, err := c.root.Resolve(meta.Path)
+	if errors.Is(err, ErrPathEscapesRoot) {
+		reportEscape(id, meta.Path, c.root)
+		return notFoundContent(), nil
+	}
+	if err != nil {
+		return notFoundContent(), nil
+	}
+	// 先 stat 再读：若读取期间文件被改写，记录的旧 mtime 会让下一次命中失效，不会长期缓存脏数据
+	st, err := os.Stat(real)
+	if err != nil {
+		return notFoundContent(), nil
+	}
+	body, err := os.ReadFile(real)
+	if err != nil {
+		return notFoundContent(), nil
+	}
+
+	e := &contentEntry{
+		meta:    meta,
+		real:    real,
+		body:    string(body),
+		modTime: st.ModTime(),
+		size:    st.Size(),
+	}
+	e.checkedAt.Store(time.Now().UnixNano())
+	c.add(e)
+	return api.BlogContent{ID: meta.ID, Text: string(body), ModTime: st.ModTime()}, nil
+}
+
+// add 放入缓存并淘汰超出上限的旧条目；单条超过上限的不缓存
+func (c *ContentCache) add(e *contentEntry) {
+	cost := e.cost()
+	if cost > c.cfg.MaxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.items[e.meta.ID]; ok {
+		c.removeLocked(old)
+	}
+	c.items[e.meta.ID] = c.ll.PushFront(e)
+	c.bytes += cost
+	c.trimLocked()
+}
+
+// trimLocked 按最近最少使用淘汰正文条目，直到总占用回到上限以内
+func (c *ContentCache) trimLocked() {
+	for c.bytes > c.cfg.MaxBytes {
+		back := c.ll.Back()
+		if back == nil {
+			break
+		}
+		c.removeLocked(back)
+		c.evictions.Add(1)
+	}
+}
+
+func (c *ContentCache) removeLocked(el *list.Element) {
+	e := c.ll.Remove(el).(*contentEntry)
+	delete(c.items, e.meta.ID)
+	c.bytes -= e.cost()
+}
+
+// Invalidate 移除指定博客的正文缓存以及列表与最新一篇，文档或文件变更时调用
+func (c *ContentCache) Invalidate(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[id]; ok {
+		c.removeLocked(el)
+	}
+	c.invalidateMetaLocked()
+}
+
+// InvalidateAll 清空缓存
+func (c *ContentCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateMetaLocked()
+	c.ll.Init()
+	c.items = make(map[int]*list.Element)
+	c.bytes = 0
+}
+
+// Stats 返回缓存统计快照
+func (c *ContentCache) Stats() metrics.CacheStats {
+	c.mu.Lock()
+	entries, bytes := c.ll.Len(), c.bytes
+	c.mu.Unlock()
+	return metrics.CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+		Bytes:     bytes,
+	}
+}
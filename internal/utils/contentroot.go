@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+)
+
+// ErrPathEscapesRoot 文档 Path 解析后位于内容根目录之外
+var ErrPathEscapesRoot = errors.New("path escapes content root")
+
+// ContentRoot 限定 markdown 文件只能从内容根目录（CONTENT_ROOT）读取。
+// 相对路径相对根目录解析，符号链接展开后再检查，防止借助文档 Path 或链接读取任意文件。
+type ContentRoot struct {
+	dir string // 绝对路径，已展开符号链接
+	abs string // 展开符号链接前的绝对路径，文档 Path 可能以它为前缀
+}
+
+// NewContentRoot 创建内容根目录，目录必须存在
+func NewContentRoot(dir string) (*ContentRoot, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content root %s: %v", dir, err)
+	}
+	st, err := os.Stat(real)
+	if err != nil {
+		return nil, err
+	}
+	if !st.IsDir() {
+		return nil, fmt.Errorf("content root %s is not a directory", dir)
+	}
+	return &ContentRoot{dir: real, abs: abs}, nil
+}
+
+// ContentRootFromEnv 读取 CONTENT_ROOT（默认与线上博客数据目录一致）
+func ContentRootFromEnv() (*ContentRoot, error) {
+	return NewContentRoot(getenv("CONTENT_ROOT", "/www/wwwroot/Personal-Blog-db"))
+}
+
+// Dir 返回根目录的绝对路径
+func (c *ContentRoot) Dir() string {
+	return c.dir
+}
+
+// Resolve 将文档 Path 解析为根目录内的真实文件路径。
+// 先对清理后的路径做字面检查，再展开符号链接检查真实路径，
+// 因此越界路径无论文件是否存在都返回 ErrPathEscapesRoot；根目录内的文件不存在时返回 fs.ErrNotExist
+func (c *ContentRoot) Resolve(p string) (string, error) {
+	if strings.TrimSpace(p) == "" {
+		return "", fmt.Errorf("empty path: %w", os.ErrNotExist)
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(c.dir, p)
+	}
+	p = filepath.Clean(p)
+	if !within(c.dir, p) && !within(c.abs, p) {
+		return "", ErrPathEscapesRoot
+	}
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	if !c.contains(real) {
+		return "", ErrPathEscapesRoot
+	}
+	st, err := os.Stat(real)
+	if err != nil {
+		return "", err
+	}
+	if !st.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file: %w", p, os.ErrNotExist)
+	}
+	return real, nil
+}
+
+// Confine 将待写入的路径解析为根目录内的路径，目标文件及其上级目录可以不存在。
+// 相对路径相对根目录解析；已存在的部分展开符号链接后检查，越界时返回 ErrPathEscapesRoot
+func (c *ContentRoot) Confine(p string) (string, error) {
+	if strings.TrimSpace(p) == "" {
+		return "", fmt.Errorf("empty path: %w", os.ErrNotExist)
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(c.dir, p)
+	}
+	existing, rest := filepath.Clean(p), ""
+	for {
+		real, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			p = filepath.Join(real, rest)
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return "", err
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	if !c.contains(p) {
+		return "", ErrPathEscapesRoot
+	}
+	return p, nil
+}
+
+// Rel 返回根目录内路径的相对形式，用于展示与存储
+func (c *ContentRoot) Rel(p string) (string, error) {
+	return filepath.Rel(c.dir, p)
+}
+
+func (c *ContentRoot) contains(p string) bool {
+	return within(c.dir, p)
+}
+
+func within(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// reportEscape 记录越界访问的安全事件
+func reportEscape(id int, path string, root *ContentRoot) {
+	metrics.ContentPathViolation()
+	log.Printf("\033[31m[Security]\033[0m blog ID=%d path %q escapes content root %s, refused", id, path, root.dir)
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// blogDateLayouts 历史文档中出现过的日期格式，按从精确到宽松排列
+var blogDateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"2006/1/2",
+	"2006-1-2",
+	"2006.01.02",
+	"2006年01月02日",
+	"2006年1月2日",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"02 Jan 2006",
+}
+
+// BlogTimezone 返回博客日期的默认时区（BLOG_TIMEZONE，默认 Asia/Shanghai），
+// 用于解析不带偏移量的历史日期以及输出 RFC 3339 时间
+func BlogTimezone() *time.Location {
+	name := getenv("BLOG_TIMEZONE", "Asia/Shanghai")
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ParseBlogDate 解析自由格式的日期字符串；不带偏移量的格式按 loc 解释
+func ParseBlogDate(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range blogDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+// blogDate 兼容两种存储形式的 Date 字段：BSON datetime（迁移后）与字符串（迁移前）
+type blogDate struct {
+	time.Time
+}
+
+// UnmarshalBSONValue 实现 bson.ValueUnmarshaler
+func (d *blogDate) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.DateTime, bsontype.Null:
+		var v time.Time
+		if t == bsontype.DateTime {
+			if err := bson.UnmarshalValue(t, data, &v); err != nil {
+				return err
+			}
+		}
+		d.Time = v
+		return nil
+	case bsontype.String:
+		var s string
+		if err := bson.UnmarshalValue(t, data, &s); err != nil {
+			return err
+		}
+		parsed, err := ParseBlogDate(s, BlogTimezone())
+		if err != nil {
+			// 无法识别的历史日期不影响整体查询，置零值
+			d.Time = time.Time{}
+			return nil
+		}
+		d.Time = parsed
+		return nil
+	default:
+		return fmt.Errorf("cannot decode %s into blog date", t)
+	}
+}
+
+// inZone 将时间转换到文档记录的时区；时区缺失或无效时使用 BlogTimezone
+func inZone(t time.Time, tz string) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	if tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return t.In(loc)
+		}
+	}
+	return t.In(BlogTimezone())
+}
@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 预报返回的点数：逐小时 24 个、逐日 7 个
+const (
+	ForecastHours = 24
+	ForecastDays  = 7
+)
+
+// 拉取的点数多于返回值：缓存期内从当前小时、当地今天起截取仍够 24 小时与 7 天
+// （跨过当地午夜后第一天已过去，多拉的一天补上）
+const (
+	forecastFetchHours = 48
+	forecastFetchDays  = ForecastDays + 1
+)
+
+// HourlyForecast 单个小时的预报
+type HourlyForecast struct {
+	Time                     time.Time
+	TempC                    *float64
+	PrecipitationProbability *float64 // 百分比
+	WeatherCode              *int     // 描述由调用方按语言通过 WeatherDescription 生成
+}
+
+// DailyForecast 单日预报；Date 为当地日期（YYYY-MM-DD）
+type DailyForecast struct {
+	Date                     string
+	TempMinC                 *float64
+	TempMaxC                 *float64
+	PrecipitationProbability *float64 // 当日最大降水概率
+	WeatherCode              *int
+	Sunrise                  *time.Time
+	Sunset                   *time.Time
+}
+
+// Forecast 逐小时与逐日预报，时间均带当地时区偏移
+type Forecast struct {
+	Timezone  string
+	Hourly    []HourlyForecast
+	Daily     []DailyForecast
+	FetchedAt time.Time
+
+	loc *time.Location // 预报所在时区，用于确定“今天”
+}
+
+// NextHours 返回从 now 所在小时开始的至多 n 个逐小时预报
+func (f *Forecast) NextHours(now time.Time, n int) []HourlyForecast {
+	start := now.Truncate(time.Hour)
+	out := make([]HourlyForecast, 0, n)
+	for _, h := range f.Hourly {
+		if h.Time.Before(start) {
+			continue
+		}
+		if len(out) == n {
+			break
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// DaysFrom 返回从 now 所在当地日期开始的至多 n 天预报
+func (f *Forecast) DaysFrom(now time.Time, n int) []DailyForecast {
+	loc := f.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	today := now.In(loc).Format("2006-01-02")
+	out := make([]DailyForecast, 0, n)
+	for _, d := range f.Daily {
+		if d.Date < today {
+			continue
+		}
+		if len(out) == n {
+			break
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// FetchForecast 从 Open-Meteo 获取逐小时与逐日预报，请求受 outboundTimeout 限制。
+// 失败时返回 *SourceError
+func FetchForecast(ctx context.Context, lat, lon float64) (*Forecast, error) {
+	url := fmt.Sprintf(
+		"%s?latitude=%f&longitude=%f"+
+			"&hourly=temperature_2m,precipitation_probability,weather_code"+
+			"&daily=weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max,sunrise,sunset"+
+			"&forecast_hours=%d&forecast_days=%d&timezone=auto",
+		openMeteoForecastURL(), lat, lon, forecastFetchHours, forecastFetchDays,
+	)
+
+	var data struct {
+		Timezone         string `json:"timezone"`
+		UTCOffsetSeconds int    `json:"utc_offset_seconds"`
+		Hourly           struct {
+			Time                     []string   `json:"time"`
+			Temperature2M            []*float64 `json:"temperature_2m"`
+			PrecipitationProbability []*float64 `json:"precipitation_probability"`
+			WeatherCode              []*int     `json:"weather_code"`
+		} `json:"hourly"`
+		Daily struct {
+			Time                        []string   `json:"time"`
+			WeatherCode                 []*int     `json:"weather_code"`
+			Temperature2MMax            []*float64 `json:"temperature_2m_max"`
+			Temperature2MMin            []*float64 `json:"temperature_2m_min"`
+			PrecipitationProbabilityMax []*float64 `json:"precipitation_probability_max"`
+			Sunrise                     []*string  `json:"sunrise"`
+			Sunset                      []*string  `json:"sunset"`
+		} `json:"daily"`
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, outboundTimeout)
+	defer cancel()
+	if err := fetchOpenMeteo(fetchCtx, newOutboundClient(), SourceForecast, url, &data); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &SourceError{Source: SourceForecast, Err: err}
+	}
+
+	// timezone=auto 时时间为不带偏移的当地时间
+	loc := forecastLocation(data.Timezone, data.UTCOffsetSeconds)
+	res := &Forecast{Timezone: data.Timezone, FetchedAt: time.Now(), loc: loc}
+	for i, ts := range data.Hourly.Time {
+		t, err := time.ParseInLocation("2006-01-02T15:04", ts, loc)
+		if err != nil {
+			return nil, &SourceError{Source: SourceForecast, Err: fmt.Errorf("hourly time %q: %w", ts, err)}
+		}
+		h := HourlyForecast{
+			Time:                     t,
+			TempC:                    at(data.Hourly.Temperature2M, i),
+			PrecipitationProbability: at(data.Hourly.PrecipitationProbability, i),
+			WeatherCode:              at(data.Hourly.WeatherCode, i),
+		}
+		res.Hourly = append(res.Hourly, h)
+	}
+	for i, date := range data.Daily.Time {
+		d := DailyForecast{
+			Date:                     date,
+			TempMinC:                 at(data.Daily.Temperature2MMin, i),
+			TempMaxC:                 at(data.Daily.Temperature2MMax, i),
+			PrecipitationProbability: at(data.Daily.PrecipitationProbabilityMax, i),
+			WeatherCode:              at(data.Daily.WeatherCode, i),
+		}
+		d.Sunrise = localTime(at(data.Daily.Sunrise, i), loc)
+		d.Sunset = localTime(at(data.Daily.Sunset, i), loc)
+		res.Daily = append(res.Daily, d)
+	}
+	return res, nil
+}
+
+// forecastLocation 按 IANA 时区名解析，预报跨越夏令时切换时偏移才正确；
+// 时区名无法识别时退回响应中的固定偏移
+func forecastLocation(name string, offset int) *time.Location {
+	if name != "" && name != "Local" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.FixedZone(name, offset)
+}
+
+// at 安全取下标，越界（数组长度不一致）时视为缺失
+func at[T any](s []*T, i int) *T {
+	if i < len(s) {
+		return s[i]
+	}
+	return nil
+}
+
+// localTime 解析不带偏移的当地时间，格式不符时视为缺失
+func localTime(s *string, loc *time.Location) *time.Time {
+	if s == nil {
+		return nil
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04", *s, loc)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
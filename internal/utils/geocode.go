@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrPlaceNotFound 地名无法解析为坐标
+var ErrPlaceNotFound = errors.New("place not found")
+
+// Place 地名解析结果
+type Place struct {
+	Name        string
+	Region      string
+	CountryCode string
+	Latitude    float64
+	Longitude   float64
+	// Source 给出结果的地理编码器名称
+	Source string
+}
+
+// Geocoder 将城市名解析为坐标，返回的地名使用 lang（LangZH / LangEN）；找不到时返回 ErrPlaceNotFound
+type Geocoder interface {
+	Name() string
+	Geocode(ctx context.Context, name, lang string) (*Place, error)
+}
+
+// 地理编码器名称，同时用于指标标签与响应中的定位来源
+const (
+	SourceGeocoding = "open-meteo-geocoding"
+	SourceOffline   = "offline"
+)
+
+// openMeteoGeocodingURL 可通过环境变量指向自建实例或测试替身
+func openMeteoGeocodingURL() string {
+	return getenv("OPEN_METEO_GEOCODING_URL", "https://geocoding-api.open-meteo.com/v1/search")
+}
+
+// OpenMeteoGeocoder 使用 Open-Meteo 地理编码接口，取最匹配的一个结果
+type OpenMeteoGeocoder struct{}
+
+func (OpenMeteoGeocoder) Name() string { return SourceGeocoding }
+
+func (OpenMeteoGeocoder) Geocode(ctx context.Context, name, lang string) (*Place, error) {
+	if lang != LangEN {
+		lang = LangZH
+	}
+	u := fmt.Sprintf("%s?name=%s&count=1&language=%s&format=json", openMeteoGeocodingURL(), url.QueryEscape(name), lang)
+	var data struct {
+		Results []struct {
+			Name        string  `json:"name"`
+			Admin1      string  `json:"admin1"`
+			CountryCode string  `json:"country_code"`
+			Latitude    float64 `json:"latitude"`
+			Longitude   float64 `json:"longitude"`
+		} `json:"results"`
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, outboundTimeout)
+	defer cancel()
+	if err := fetchOpenMeteo(fetchCtx, newOutboundClient(), SourceGeocoding, u, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Results) == 0 {
+		return nil, ErrPlaceNotFound
+	}
+	r := data.Results[0]
+	return &Place{
+		Name:        r.Name,
+		Region:      r.Admin1,
+		CountryCode: r.CountryCode,
+		Latitude:    r.Latitude,
+		Longitude:   r.Longitude,
+		Source:      SourceGeocoding,
+	}, nil
+}
+
+// cities.csv：英文名,中文名,国家代码,省/州,纬度,经度
+//
+//go:embed cities.csv
+var citiesCSV string
+
+// OfflineGeocoder 基于内置城市列表的地理编码器，中英文名均可匹配（不区分大小写）
+type OfflineGeocoder struct {
+	places map[string]*offlinePlace
+}
+
+// offlinePlace 内置城市，Place.Name 为中文名（缺失时为英文名）
+type offlinePlace struct {
+	Place
+	nameEN string
+}
+
+// NewOfflineGeocoder 解析内置城市列表
+func NewOfflineGeocoder() *OfflineGeocoder {
+	g := &OfflineGeocoder{places: make(map[string]*offlinePlace)}
+	r := csv.NewReader(strings.NewReader(citiesCSV))
+	r.Comment = '#'
+	records, err := r.ReadAll()
+	if err != nil {
+		// 内置数据在构建时即已确定，解析失败只能是数据本身有误
+		log.Printf("[Geocode] parse bundled city list failed: %v", err)
+		return g
+	}
+	for _, rec := range records {
+		if len(rec) != 6 {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(rec[4], 64)
+		lon, err2 := strconv.ParseFloat(rec[5], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		p := &offlinePlace{
+			Place:  Place{Name: rec[1], Region: rec[3], CountryCode: rec[2], Latitude: lat, Longitude: lon, Source: SourceOffline},
+			nameEN: rec[0],
+		}
+		if p.Name == "" {
+			p.Name = rec[0]
+		}
+		for _, n := range rec[:2] {
+			if n != "" {
+				g.places[normalizePlaceName(n)] = p
+			}
+		}
+	}
+	return g
+}
+
+func (g *OfflineGeocoder) Name() string { return SourceOffline }
+
+func (g *OfflineGeocoder) Geocode(ctx context.Context, name, lang string) (*Place, error) {
+	p, ok := g.places[normalizePlaceName(name)]
+	if !ok {
+		return nil, ErrPlaceNotFound
+	}
+	cp := p.Place
+	if lang == LangEN && p.nameEN != "" {
+		cp.Name = p.nameEN
+	}
+	return &cp, nil
+}
+
+// normalizePlaceName 统一大小写与空白，并去掉中文“市”后缀
+func normalizePlaceName(name string) string {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	return strings.TrimSuffix(name, "市")
+}
+
+// GeocoderChain 依次尝试各地理编码器：前一个出错或找不到时使用下一个
+type GeocoderChain []Geocoder
+
+func (c GeocoderChain) Name() string { return "chain" }
+
+// Geocode 全部找不到时返回 ErrPlaceNotFound；只要有编码器出错（而非找不到）则返回合并后的错误
+func (c GeocoderChain) Geocode(ctx context.Context, name, lang string) (*Place, error) {
+	var errs []error
+	for _, g := range c {
+		p, err := g.Geocode(ctx, name, lang)
+		if err == nil {
+			return p, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !errors.Is(err, ErrPlaceNotFound) {
+			log.Printf("[Geocode] %s lookup %q failed: %v", g.Name(), name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", g.Name(), err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, ErrPlaceNotFound
+	}
+	return nil, errors.Join(errs...)
+}
+
+var (
+	defaultGeocoderOnce sync.Once
+	defaultGeocoder     Geocoder
+)
+
+// DefaultGeocoder 在线 Open-Meteo 优先，失败或找不到时回退到内置城市列表
+func DefaultGeocoder() Geocoder {
+	defaultGeocoderOnce.Do(func() {
+		defaultGeocoder = GeocoderChain{OpenMeteoGeocoder{}, NewOfflineGeocoder()}
+	})
+	return defaultGeocoder
+}
@@ -0,0 +1,361 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+	"github.com/LtePrince/Personal-Website-backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ----- IP 地理位置解析 -----
+// 设计目标：
+// 1. 私有 / 回环地址快速返回占位，不发外网请求
+// 2. 提供商实现 IPGeoProvider 并注册，按 IPGEO_PROVIDERS 配置的顺序依次尝试
+// 3. 每个提供商独立配置 base URL、超时、重试次数与退避，便于测试时指向本地替身服务
+// 4. ctx 取消后立即停止重试与后备
+
+// IPGeoProvider 一个 IP 地理定位来源
+type IPGeoProvider interface {
+	Name() string
+	Lookup(ctx context.Context, ip string) (*IPInfo, error)
+}
+
+// ProviderError 提供商返回的错误，Retriable 表示是否建议重试（网络错误、429、5xx）
+type ProviderError struct {
+	Provider  string
+	Retriable bool
+	Err       error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Provider + ": " + e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// IPGeoProviderConfig 单个提供商在链中的配置
+type IPGeoProviderConfig struct {
+	// Name 注册名，同时用于日志、指标与 trace
+	Name    string
+	BaseURL string
+	// Timeout 单次尝试的截止时间，叠加在调用方 ctx 之上
+	Timeout time.Duration
+	// Retries 首次失败后的最多重试次数（仅对可重试错误）
+	Retries int
+	// Backoff 第 n 次重试前等待 n×Backoff
+	Backoff time.Duration
+	// Path 本地数据库文件（maxmind）
+	Path string
+	// ReloadInterval 检查本地数据库文件是否变化的间隔（maxmind）
+	ReloadInterval time.Duration
+}
+
+// IPGeoFactory 根据配置创建提供商；client 已带 trace 传播
+type IPGeoFactory func(cfg IPGeoProviderConfig, client *http.Client) IPGeoProvider
+
+type ipGeoRegistration struct {
+	defaults IPGeoProviderConfig
+	factory  IPGeoFactory
+}
+
+var (
+	ipGeoMu       sync.RWMutex
+	ipGeoRegistry = map[string]ipGeoRegistration{}
+)
+
+// RegisterIPGeoProvider 注册提供商及其默认配置，通常在 init 中调用
+func RegisterIPGeoProvider(defaults IPGeoProviderConfig, factory IPGeoFactory) {
+	ipGeoMu.Lock()
+	defer ipGeoMu.Unlock()
+	ipGeoRegistry[defaults.Name] = ipGeoRegistration{defaults: defaults, factory: factory}
+}
+
+// IPGeoProviderDefaults 返回已注册提供商的默认配置
+func IPGeoProviderDefaults(name string) (IPGeoProviderConfig, bool) {
+	ipGeoMu.RLock()
+	defer ipGeoMu.RUnlock()
+	reg, ok := ipGeoRegistry[name]
+	return reg.defaults, ok
+}
+
+// RegisteredIPGeoProviders 返回全部已注册的提供商名称
+func RegisteredIPGeoProviders() []string {
+	ipGeoMu.RLock()
+	defer ipGeoMu.RUnlock()
+	names := make([]string, 0, len(ipGeoRegistry))
+	for name := range ipGeoRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterIPGeoProvider(IPGeoProviderConfig{
+		Name: "ipapi", BaseURL: "https://ipapi.co", Timeout: outboundTimeout, Retries: 1, Backoff: 200 * time.Millisecond,
+	}, func(cfg IPGeoProviderConfig, client *http.Client) IPGeoProvider {
+		return &ipapiProvider{cfg: cfg, client: client}
+	})
+	RegisterIPGeoProvider(IPGeoProviderConfig{
+		Name: "ipwhois", BaseURL: "https://ipwho.is", Timeout: outboundTimeout,
+	}, func(cfg IPGeoProviderConfig, client *http.Client) IPGeoProvider {
+		return &ipwhoisProvider{cfg: cfg, client: client}
+	})
+}
+
+// IPGeoConfigFromEnv 读取 IPGEO_PROVIDERS（逗号分隔，按顺序尝试，默认 ipapi,ipwhois），
+// 以及每个提供商的 IPGEO_<NAME>_BASE_URL / _TIMEOUT / _RETRIES / _BACKOFF / _PATH / _RELOAD。
+// 设置了 IPGEO_MAXMIND_PATH 而列表中没有 maxmind 时，将其放在最前，在线提供商仅作后备
+func IPGeoConfigFromEnv() ([]IPGeoProviderConfig, error) {
+	names := strings.Split(getenv("IPGEO_PROVIDERS", "ipapi,ipwhois"), ",")
+	if os.Getenv("IPGEO_MAXMIND_PATH") != "" && !slices.ContainsFunc(names, func(n string) bool { return strings.TrimSpace(n) == "maxmind" }) {
+		names = append([]string{"maxmind"}, names...)
+	}
+	var cfgs []IPGeoProviderConfig
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		cfg, ok := IPGeoProviderDefaults(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown ip geolocation provider %q (registered: %s)", name, strings.Join(RegisteredIPGeoProviders(), ", "))
+		}
+		prefix := "IPGEO_" + strings.ToUpper(name) + "_"
+		cfg.BaseURL = strings.TrimRight(getenv(prefix+"BASE_URL", cfg.BaseURL), "/")
+		cfg.Timeout = envDuration(prefix+"TIMEOUT", cfg.Timeout.String())
+		cfg.Retries = envInt(prefix+"RETRIES", cfg.Retries)
+		cfg.Backoff = envDuration(prefix+"BACKOFF", cfg.Backoff.String())
+		cfg.Path = getenv(prefix+"PATH", cfg.Path)
+		cfg.ReloadInterval = envDuration(prefix+"RELOAD", cfg.ReloadInterval.String())
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, nil
+}
+
+// IPGeoChain 按顺序尝试的提供商链
+type IPGeoChain struct {
+	entries []ipGeoEntry
+}
+
+type ipGeoEntry struct {
+	cfg      IPGeoProviderConfig
+	provider IPGeoProvider
+}
+
+// NewIPGeoChain 按配置顺序创建提供商链；client 为 nil 时使用带 trace 传播的默认客户端
+func NewIPGeoChain(cfgs []IPGeoProviderConfig, client *http.Client) (*IPGeoChain, error) {
+	if client == nil {
+		client = newOutboundClient()
+	}
+	chain := &IPGeoChain{}
+	for _, cfg := range cfgs {
+		ipGeoMu.RLock()
+		reg, ok := ipGeoRegistry[cfg.Name]
+		ipGeoMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown ip geolocation provider %q", cfg.Name)
+		}
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = outboundTimeout
+		}
+		chain.entries = append(chain.entries, ipGeoEntry{cfg: cfg, provider: reg.factory(cfg, client)})
+	}
+	return chain, nil
+}
+
+// Lookup 依次尝试各提供商，返回第一个成功的结果；全部失败时返回合并后的错误
+func (c *IPGeoChain) Lookup(ctx context.Context, ip string) (*IPInfo, error) {
+	ip = strings.TrimSpace(ip)
+	if ip == "" {
+		return nil, errors.New("empty ip")
+	}
+	if IsPrivateOrLoopbackIP(ip) {
+		// 私有地址：不请求外部服务
+		return &IPInfo{IP: ip, City: "Local Network", Source: "local"}, nil
+	}
+	var errs []error
+	for _, e := range c.entries {
+		info, err := c.try(ctx, e, ip)
+		if info != nil {
+			return info, nil
+		}
+		errs = append(errs, err)
+		// 调用方已取消或超时：不再请求后备
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no ip geolocation providers configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// try 对单个提供商按配置重试
+func (c *IPGeoChain) try(ctx context.Context, e ipGeoEntry, ip string) (*IPInfo, error) {
+	name := e.provider.Name()
+	for attempt := 0; ; attempt++ {
+		attemptCtx, span := tracing.Start(ctx, "ipgeo.attempt",
+			attribute.String("ipgeo.provider", name),
+			attribute.Int("ipgeo.attempt", attempt+1),
+		)
+		attemptCtx, cancel := context.WithTimeout(attemptCtx, e.cfg.Timeout)
+		info, err := e.provider.Lookup(attemptCtx, ip)
+		cancel()
+		tracing.End(span, err)
+		if err == nil && info != nil {
+			metrics.UpstreamResult(name, metrics.OutcomeSuccess)
+			info.Source = name
+			return info, nil
+		}
+		if err == nil {
+			err = &ProviderError{Provider: name, Err: errors.New("empty result")}
+		}
+		var pe *ProviderError
+		retriable := errors.As(err, &pe) && pe.Retriable
+		log.Printf("[IPGeo] %s attempt %d error: %v (retriable=%v)", name, attempt+1, err, retriable)
+		if !retriable || attempt >= e.cfg.Retries || ctx.Err() != nil {
+			metrics.UpstreamResult(name, metrics.OutcomeFailure)
+			return nil, err
+		}
+		metrics.UpstreamResult(name, metrics.OutcomeRetry)
+		// 退避
+		if err := sleepCtx(ctx, time.Duration(attempt+1)*e.cfg.Backoff); err != nil {
+			return nil, err
+		}
+	}
+}
+
+var (
+	defaultIPGeoOnce  sync.Once
+	defaultIPGeoChain *IPGeoChain
+)
+
+// DefaultIPGeoChain 按环境变量创建的进程级提供商链；配置错误时记录日志并退回内置默认顺序
+func DefaultIPGeoChain() *IPGeoChain {
+	defaultIPGeoOnce.Do(func() {
+		cfgs, err := IPGeoConfigFromEnv()
+		if err == nil {
+			defaultIPGeoChain, err = NewIPGeoChain(cfgs, nil)
+		}
+		if err != nil {
+			log.Printf("[IPGeo] invalid configuration, using defaults: %v", err)
+			a, _ := IPGeoProviderDefaults("ipapi")
+			b, _ := IPGeoProviderDefaults("ipwhois")
+			defaultIPGeoChain, _ = NewIPGeoChain([]IPGeoProviderConfig{a, b}, nil)
+		}
+	})
+	return defaultIPGeoChain
+}
+
+// LookupIPLocation 统一对外调用：
+//   - 私有/回环: 立即返回占位
+//   - 命中缓存（含失败结果的短期缓存）时直接返回
+//   - 按 IPGEO_PROVIDERS 顺序尝试各提供商（默认 ipapi 重试 1 次，失败后 ipwho.is）
+//   - 返回 IPInfo 或错误
+func LookupIPLocation(ctx context.Context, ip string) (*IPInfo, error) {
+	return DefaultIPGeoCache().Lookup(ctx, ip)
+}
+
+// getJSON 执行 GET 并限制读取的响应体大小；非 200 时返回带重试建议的 ProviderError
+func getJSON(ctx context.Context, client *http.Client, provider, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, &ProviderError{Provider: provider, Err: err}
+	}
+	req.Header.Set("User-Agent", "PersonalSite-Geolocate/1.0")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &ProviderError{Provider: provider, Retriable: true, Err: err}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	if resp.StatusCode != http.StatusOK {
+		// 429/5xx 视为可重试，其它直接失败
+		retriable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, &ProviderError{Provider: provider, Retriable: retriable, Err: fmt.Errorf("status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))}
+	}
+	return body, nil
+}
+
+// ipapiProvider ipapi.co：GET {base}/{ip}/json/
+type ipapiProvider struct {
+	cfg    IPGeoProviderConfig
+	client *http.Client
+}
+
+func (p *ipapiProvider) Name() string { return p.cfg.Name }
+
+func (p *ipapiProvider) Lookup(ctx context.Context, ip string) (*IPInfo, error) {
+	body, err := getJSON(ctx, p.client, p.cfg.Name, fmt.Sprintf("%s/%s/json/", p.cfg.BaseURL, ip))
+	if err != nil {
+		return nil, err
+	}
+	var info IPInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, &ProviderError{Provider: p.cfg.Name, Err: err}
+	}
+	log.Printf("[IPGeo] ipapi success ip=%s city=%s region=%s countryCode=%s lat=%.4f lon=%.4f", ip, info.City, info.Region, info.CountryCode, info.Latitude, info.Longitude)
+	// 至少 city 或 countryCode 有值才认为有效
+	if info.City == "" && info.CountryCode == "" {
+		return nil, &ProviderError{Provider: p.cfg.Name, Err: errors.New("empty essential fields")}
+	}
+	return &info, nil
+}
+
+// ipwhoisProvider ipwho.is：GET {base}/{ip}
+type ipwhoisProvider struct {
+	cfg    IPGeoProviderConfig
+	client *http.Client
+}
+
+func (p *ipwhoisProvider) Name() string { return p.cfg.Name }
+
+func (p *ipwhoisProvider) Lookup(ctx context.Context, ip string) (*IPInfo, error) {
+	body, err := getJSON(ctx, p.client, p.cfg.Name, fmt.Sprintf("%s/%s", p.cfg.BaseURL, ip))
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		City        string  `json:"city"`
+		Region      string  `json:"region"`
+		Country     string  `json:"country"`
+		CountryCode string  `json:"country_code"`
+		Latitude    float64 `json:"latitude"`
+		Longitude   float64 `json:"longitude"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, &ProviderError{Provider: p.cfg.Name, Err: err}
+	}
+	info := &IPInfo{
+		IP:          ip,
+		City:        raw.City,
+		Region:      raw.Region,
+		Country:     raw.Country,
+		CountryCode: raw.CountryCode,
+		Latitude:    raw.Latitude,
+		Longitude:   raw.Longitude,
+	}
+	log.Printf("[IPGeo] ipwho.is success ip=%s city=%s region=%s countryCode=%s lat=%.4f lon=%.4f", ip, info.City, info.Region, info.CountryCode, info.Latitude, info.Longitude)
+	if info.City == "" && info.CountryCode == "" {
+		return nil, &ProviderError{Provider: p.cfg.Name, Err: errors.New("empty essential fields")}
+	}
+	return info, nil
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// IPLocator 按 IP 查询位置，IPGeoChain 与 IPGeoCache 均实现该接口
+type IPLocator interface {
+	Lookup(ctx context.Context, ip string) (*IPInfo, error)
+}
+
+// IPGeoCacheConfig IP 定位结果缓存配置
+type IPGeoCacheConfig struct {
+	// MaxEntries 条目上限，超出时按最近最少使用淘汰；<=0 关闭缓存
+	MaxEntries int
+	// TTL 成功结果的有效期
+	TTL time.Duration
+	// NegativeTTL 失败结果的有效期，避免对查不到的 IP 反复请求外部服务
+	NegativeTTL time.Duration
+}
+
+// IPGeoCacheConfigFromEnv 读取 IPGEO_CACHE_SIZE / IPGEO_CACHE_TTL / IPGEO_CACHE_NEGATIVE_TTL
+func IPGeoCacheConfigFromEnv() IPGeoCacheConfig {
+	return IPGeoCacheConfig{
+		MaxEntries:  envInt("IPGEO_CACHE_SIZE", 10000),
+		TTL:         envDuration("IPGEO_CACHE_TTL", "6h"),
+		NegativeTTL: envDuration("IPGEO_CACHE_NEGATIVE_TTL", "5m"),
+	}
+}
+
+// ipGeoCacheEntry 缓存条目：info 与 err 二选一
+type ipGeoCacheEntry struct {
+	ip        string
+	info      *IPInfo
+	err       error
+	expiresAt time.Time
+}
+
+// IPGeoCache 在 IPLocator 前加一层有界 TTL 缓存；同一 IP 的并发查询合并为一次
+type IPGeoCache struct {
+	next IPLocator
+	cfg  IPGeoCacheConfig
+
+	mu    sync.Mutex
+	ll    *list.List // 前端为最近使用
+	items map[string]*list.Element
+
+	hits, misses, evictions atomic.Uint64
+
+	group singleflight.Group
+}
+
+// NewIPGeoCache 包装 next
+func NewIPGeoCache(next IPLocator, cfg IPGeoCacheConfig) *IPGeoCache {
+	return &IPGeoCache{
+		next:  next,
+		cfg:   cfg,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Lookup 优先返回未过期的缓存结果（包括缓存的失败）
+func (c *IPGeoCache) Lookup(ctx context.Context, ip string) (*IPInfo, error) {
+	if c.cfg.MaxEntries <= 0 || IsPrivateOrLoopbackIP(ip) {
+		return c.next.Lookup(ctx, ip)
+	}
+	if e, ok := c.get(ip); ok {
+		c.hits.Add(1)
+		return copyIPInfo(e.info), e.err
+	}
+	c.misses.Add(1)
+
+	// 合并后的查询不随单个调用方取消，避免一个断开的请求让其它等待者一起失败；
+	// 每个调用方仍按自己的 ctx 放弃等待
+	ch := c.group.DoChan(ip, func() (any, error) {
+		info, err := c.next.Lookup(context.WithoutCancel(ctx), ip)
+		c.put(ip, info, err)
+		return info, err
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		info, _ := res.Val.(*IPInfo)
+		return copyIPInfo(info), res.Err
+	}
+}
+
+func copyIPInfo(info *IPInfo) *IPInfo {
+	if info == nil {
+		return nil
+	}
+	cp := *info
+	return &cp
+}
+
+func (c *IPGeoCache) get(ip string) (*ipGeoCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[ip]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*ipGeoCacheEntry)
+	if time.Now().After(e.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.items, ip)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e, true
+}
+
+// put 写入结果；取消或超时导致的失败不缓存
+func (c *IPGeoCache) put(ip string, info *IPInfo, err error) {
+	ttl := c.cfg.TTL
+	if err != nil || info == nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return
+		}
+		ttl = c.cfg.NegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	e := &ipGeoCacheEntry{ip: ip, info: info, err: err, expiresAt: time.Now().Add(ttl)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[ip]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[ip] = c.ll.PushFront(e)
+	for c.ll.Len() > c.cfg.MaxEntries {
+		back := c.ll.Back()
+		c.ll.Remove(back)
+		delete(c.items, back.Value.(*ipGeoCacheEntry).ip)
+		c.evictions.Add(1)
+	}
+}
+
+// Stats 返回缓存统计快照；条目大小不固定且未计量，Bytes 为 0
+func (c *IPGeoCache) Stats() metrics.CacheStats {
+	c.mu.Lock()
+	entries := c.ll.Len()
+	c.mu.Unlock()
+	return metrics.CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+var (
+	defaultIPGeoCacheOnce sync.Once
+	defaultIPGeoCache     *IPGeoCache
+)
+
+// DefaultIPGeoCache 包装 DefaultIPGeoChain 的进程级缓存，由 LookupIPLocation 使用
+func DefaultIPGeoCache() *IPGeoCache {
+	defaultIPGeoCacheOnce.Do(func() {
+		defaultIPGeoCache = NewIPGeoCache(DefaultIPGeoChain(), IPGeoCacheConfigFromEnv())
+	})
+	return defaultIPGeoCache
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// maxmindCity GeoLite2-City / GeoIP2-City 记录中用到的字段
+type maxmindCity struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+func init() {
+	RegisterIPGeoProvider(IPGeoProviderConfig{
+		Name: "maxmind", Timeout: time.Second, ReloadInterval: time.Minute,
+	}, func(cfg IPGeoProviderConfig, _ *http.Client) IPGeoProvider {
+		return NewMaxMindProvider(cfg)
+	})
+}
+
+// MaxMindProvider 从本地 .mmdb（GeoLite2 City 格式）离线查询，不向外发送访客 IP。
+// 文件 mtime/size 变化后自动重新加载；整个文件读入内存，替换时无需等待进行中的查询
+type MaxMindProvider struct {
+	cfg IPGeoProviderConfig
+
+	db atomic.Pointer[maxminddb.Reader]
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	modTime   time.Time
+	size      int64
+}
+
+// NewMaxMindProvider 创建提供商并尝试加载 cfg.Path；文件暂不可用时查询返回错误，链会继续尝试后续提供商
+func NewMaxMindProvider(cfg IPGeoProviderConfig) *MaxMindProvider {
+	p := &MaxMindProvider{cfg: cfg}
+	if err := p.reload(); err != nil {
+		log.Printf("[IPGeo] maxmind database unavailable: %v", err)
+	}
+	return p
+}
+
+func (p *MaxMindProvider) Name() string { return p.cfg.Name }
+
+func (p *MaxMindProvider) Lookup(_ context.Context, ip string) (*IPInfo, error) {
+	p.maybeReload()
+	db := p.db.Load()
+	if db == nil {
+		return nil, &ProviderError{Provider: p.cfg.Name, Err: errors.New("database not loaded")}
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, &ProviderError{Provider: p.cfg.Name, Err: fmt.Errorf("invalid ip %q", ip)}
+	}
+	var rec maxmindCity
+	if err := db.Lookup(parsed, &rec); err != nil {
+		return nil, &ProviderError{Provider: p.cfg.Name, Err: err}
+	}
+	info := &IPInfo{
+		IP:          ip,
+		City:        rec.City.Names["en"],
+		Country:     rec.Country.Names["en"],
+		CountryCode: rec.Country.ISOCode,
+		Latitude:    rec.Location.Latitude,
+		Longitude:   rec.Location.Longitude,
+	}
+	if len(rec.Subdivisions) > 0 {
+		info.Region = rec.Subdivisions[0].Names["en"]
+	}
+	if info.City == "" && info.CountryCode == "" {
+		return nil, &ProviderError{Provider: p.cfg.Name, Err: errors.New("ip not found in database")}
+	}
+	return info, nil
+}
+
+// maybeReload 每隔 ReloadInterval 检查一次文件是否变化
+func (p *MaxMindProvider) maybeReload() {
+	p.mu.Lock()
+	if p.cfg.ReloadInterval <= 0 || time.Since(p.checkedAt) < p.cfg.ReloadInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.checkedAt = time.Now()
+	st, err := os.Stat(p.cfg.Path)
+	changed := err == nil && (!st.ModTime().Equal(p.modTime) || st.Size() != p.size)
+	p.mu.Unlock()
+	if !changed {
+		return
+	}
+	if err := p.reload(); err != nil {
+		// 新文件可能尚未写完：保留旧库，下次检查再试
+		log.Printf("[IPGeo] maxmind reload failed, keeping previous database: %v", err)
+	}
+}
+
+// reload 读取并替换数据库
+func (p *MaxMindProvider) reload() error {
+	if p.cfg.Path == "" {
+		return errors.New("no database path configured")
+	}
+	st, err := os.Stat(p.cfg.Path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p.cfg.Path)
+	if err != nil {
+		return err
+	}
+	db, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return err
+	}
+	p.db.Store(db)
+	p.mu.Lock()
+	p.modTime, p.size, p.checkedAt = st.ModTime(), st.Size(), time.Now()
+	p.mu.Unlock()
+	log.Printf("[IPGeo] maxmind database loaded: %s (%s, built %s)", p.cfg.Path, db.Metadata.DatabaseType,
+		time.Unix(int64(db.Metadata.BuildEpoch), 0).UTC().Format(time.DateOnly))
+	return nil
+}
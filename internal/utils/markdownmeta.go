@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarkdownMeta 从 markdown 文件解析出的元数据，缺失字段为零值
+type MarkdownMeta struct {
+	ID      int
+	Title   string
+	Summary string
+	Slug    string
+	Date    time.Time
+}
+
+// ParseMarkdownMeta 解析文件头部的 front matter（--- 包围的 key: value 行）。
+// 没有 title 时取第一个一级标题；没有 summary 时取代码块之外的正文第一段（截断到 120 字）
+func ParseMarkdownMeta(content []byte, loc *time.Location) MarkdownMeta {
+	var meta MarkdownMeta
+	body := content
+	if fm, rest, ok := splitFrontMatter(content); ok {
+		body = rest
+		for _, line := range strings.Split(fm, "\n") {
+			key, value, found := strings.Cut(line, ":")
+			if !found {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "id":
+				meta.ID, _ = strconv.Atoi(value)
+			case "title":
+				meta.Title = value
+			case "summary", "description":
+				meta.Summary = value
+			case "slug":
+				meta.Slug = value
+			case "date":
+				meta.Date, _ = ParseBlogDate(value, loc)
+			}
+		}
+	}
+
+	var para []string
+	inFence := false
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			// 代码块围栏：其中的内容（包括 # 开头的注释）既不是标题也不是摘要
+			inFence = !inFence
+			line = ""
+		} else if inFence {
+			continue
+		}
+		switch {
+		case meta.Title == "" && strings.HasPrefix(line, "# "):
+			meta.Title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		case line == "":
+			if len(para) > 0 && meta.Summary == "" {
+				meta.Summary = summarize(strings.Join(para, " "))
+			}
+			para = para[:0]
+		case strings.HasPrefix(line, "#"), strings.HasPrefix(line, "!["):
+			// 标题与图片不作为摘要
+		default:
+			para = append(para, line)
+		}
+		if meta.Title != "" && meta.Summary != "" {
+			break
+		}
+	}
+	if meta.Summary == "" && len(para) > 0 {
+		meta.Summary = summarize(strings.Join(para, " "))
+	}
+	return meta
+}
+
+// splitFrontMatter 拆出 front matter 与正文
+func splitFrontMatter(content []byte) (string, []byte, bool) {
+	s := strings.TrimPrefix(string(content), "\ufeff")
+	if !strings.HasPrefix(s, "---") {
+		return "", content, false
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	rest := s[3:]
+	nl := strings.IndexByte(rest, '\n')
+	if nl < 0 || strings.TrimSpace(rest[:nl]) != "" {
+		return "", content, false
+	}
+	rest = rest[nl+1:]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return "", content, false
+	}
+	fm := rest[:end]
+	body := rest[end+4:]
+	if i := strings.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = ""
+	}
+	return fm, []byte(body), true
+}
+
+func summarize(s string) string {
+	const max = 120
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
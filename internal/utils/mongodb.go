@@ -2,102 +2,242 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/LtePrince/Personal-Website-backend/api"
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+	"github.com/LtePrince/Personal-Website-backend/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
-var (
-	client     *mongo.Client
-	clientLock sync.Mutex
-	timer      *time.Timer
-)
+// ErrMongoUnavailable 处于重连退避期内时快速失败，避免每个请求都等待连接超时
+var ErrMongoUnavailable = errors.New("mongodb unavailable, retry later")
 
-var (
-	// 从环境变量读取 Mongo 配置，提供合理默认值
-	mongoURI       = getenv("MONGO_URI", "mongodb://localhost:27017")
-	databaseName   = getenv("MONGO_DB", "WebsiteBlog")
-	collectionName = getenv("MONGO_COLLECTION", "blogs")
-	idleTimeout    = envDuration("MONGO_IDLE_TIMEOUT", "1h")
-)
+// MongoConfig 描述 Mongo 连接与连接池配置
+type MongoConfig struct {
+	URI        string
+	Database   string
+	Collection string
+
+	// 连接池：驱动按需在 MinPoolSize 与 MaxPoolSize 之间维护连接，
+	// 空闲超过 MaxConnIdleTime 的连接由驱动自行关闭
+	MinPoolSize     uint64
+	MaxPoolSize     uint64
+	MaxConnIdleTime time.Duration
+
+	ConnectTimeout time.Duration
+	QueryTimeout   time.Duration
 
-func getenv(key, def string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+	// 连接失败后的重试退避区间
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// MongoConfigFromEnv 从环境变量读取 Mongo 配置，提供合理默认值
+func MongoConfigFromEnv() MongoConfig {
+	return MongoConfig{
+		URI:             getenv("MONGO_URI", "mongodb://localhost:27017"),
+		Database:        getenv("MONGO_DB", "WebsiteBlog"),
+		Collection:      getenv("MONGO_COLLECTION", "blogs"),
+		MinPoolSize:     uint64(envInt("MONGO_MIN_POOL_SIZE", 1)),
+		MaxPoolSize:     uint64(envInt("MONGO_MAX_POOL_SIZE", 20)),
+		MaxConnIdleTime: envDuration("MONGO_IDLE_TIMEOUT", "1h"),
+		ConnectTimeout:  envDuration("MONGO_CONNECT_TIMEOUT", "10s"),
+		QueryTimeout:    envDuration("MONGO_QUERY_TIMEOUT", "10s"),
+		MinBackoff:      envDuration("MONGO_RECONNECT_MIN_BACKOFF", "1s"),
+		MaxBackoff:      envDuration("MONGO_RECONNECT_MAX_BACKOFF", "1m"),
 	}
-	return def
 }
 
-func envDuration(key, def string) time.Duration {
-	v := os.Getenv(key)
-	if v == "" {
-		v = def
+// MongoManager 持有 Mongo 客户端，负责建连、失败退避与关闭，可并发使用。
+// 客户端一旦建立便常驻，连接的增减交给驱动连接池处理。
+type MongoManager struct {
+	cfg  MongoConfig
+	root *ContentRoot
+
+	mu          sync.RWMutex
+	client      *mongo.Client
+	backoff     time.Duration
+	nextAttempt time.Time
+
+	connecting singleflight.Group
+}
+
+// NewMongoManager 创建连接管理器，不立即建连；root 为 markdown 所在的内容根目录，
+// 仅做维护任务（迁移、导入导出）时可传 nil，此时不提供博客正文
+func NewMongoManager(cfg MongoConfig, root *ContentRoot) *MongoManager {
+	return &MongoManager{cfg: cfg, root: root}
+}
+
+// Connect 建立连接并 Ping 确认可用；已连接时直接返回
+func (m *MongoManager) Connect(ctx context.Context) error {
+	_, err := m.getClient(ctx)
+	return err
+}
+
+// getClient 返回可用客户端，必要时建连；连接失败后按指数退避拒绝新的尝试。
+// 并发的建连请求合并为一次，且不持锁、不随单个调用方取消，调用方只按自己的 ctx 放弃等待
+func (m *MongoManager) getClient(ctx context.Context) (*mongo.Client, error) {
+	m.mu.RLock()
+	c, next := m.client, m.nextAttempt
+	m.mu.RUnlock()
+	if c != nil {
+		return c, nil
+	}
+	if time.Now().Before(next) {
+		return nil, ErrMongoUnavailable
 	}
-	d, err := time.ParseDuration(v)
+
+	ch := m.connecting.DoChan("connect", func() (any, error) {
+		return m.dial(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*mongo.Client), nil
+	}
+}
+
+// dial 建连并 Ping 确认可用，只在更新状态时持锁；超时只来自 ConnectTimeout，
+// 因此失败一定是服务端不可用，可以放心计入退避
+func (m *MongoManager) dial(ctx context.Context) (*mongo.Client, error) {
+	m.mu.RLock()
+	c, next := m.client, m.nextAttempt
+	m.mu.RUnlock()
+	if c != nil {
+		return c, nil
+	}
+	if time.Now().Before(next) {
+		return nil, ErrMongoUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.ConnectTimeout)
+	defer cancel()
+
+	opts := options.Client().
+		ApplyURI(m.cfg.URI).
+		SetConnectTimeout(m.cfg.ConnectTimeout).
+		SetMinPoolSize(m.cfg.MinPoolSize).
+		SetMaxPoolSize(m.cfg.MaxPoolSize).
+		SetMaxConnIdleTime(m.cfg.MaxConnIdleTime).
+		SetPoolMonitor(poolMonitor())
+	c, err := mongo.Connect(ctx, opts)
+	if err == nil {
+		if err = c.Ping(ctx, nil); err != nil {
+			_ = c.Disconnect(context.Background())
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if err != nil {
-		d, _ = time.ParseDuration(def)
+		m.failLocked()
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
-	return d
+	m.client = c
+	m.backoff = 0
+	m.nextAttempt = time.Time{}
+	log.Printf("MongoDB connected (pool min=%d max=%d idle=%s)", m.cfg.MinPoolSize, m.cfg.MaxPoolSize, m.cfg.MaxConnIdleTime)
+	return c, nil
 }
 
-// ConnectMongoDB 连接 MongoDB 并将客户端保存为全局变量
-func ConnectMongoDB() error {
-	clientLock.Lock()
-	defer clientLock.Unlock()
+// failLocked 记录一次建连失败并推迟下次尝试，调用方需持有写锁
+func (m *MongoManager) failLocked() {
+	if m.backoff == 0 {
+		m.backoff = m.cfg.MinBackoff
+	} else {
+		m.backoff *= 2
+	}
+	if m.backoff > m.cfg.MaxBackoff {
+		m.backoff = m.cfg.MaxBackoff
+	}
+	m.nextAttempt = time.Now().Add(m.backoff)
+	log.Printf("MongoDB connect failed, next attempt in %s", m.backoff)
+}
 
-	if client != nil {
-		resetIdleTimer()
+// Close 断开客户端并释放连接池
+func (m *MongoManager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.client == nil {
 		return nil
 	}
+	err := m.client.Disconnect(ctx)
+	m.client = nil
+	return err
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// Blogs 返回博客集合，供迁移、导入导出等维护任务使用
+func (m *MongoManager) Blogs(ctx context.Context) (*mongo.Collection, error) {
+	return m.collection(ctx)
+}
 
-	var err error
-	client, err = mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+// collection 返回博客集合，必要时建连
+func (m *MongoManager) collection(ctx context.Context) (*mongo.Collection, error) {
+	c, err := m.getClient(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to connect to MongoDB: %v", err)
+		return nil, err
 	}
-
-	resetIdleTimer()
-	return nil
+	return c.Database(m.cfg.Database).Collection(m.cfg.Collection), nil
 }
 
-// resetIdleTimer 重置空闲定时器
-func resetIdleTimer() {
-	if timer != nil {
-		timer.Stop()
+// poolMonitor 将连接池中连接的建立与关闭计入指标
+func poolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				metrics.MongoConnEvent(metrics.EventConnect)
+			case event.ConnectionClosed:
+				metrics.MongoConnEvent(metrics.EventDisconnect)
+			}
+		},
 	}
-	timer = time.AfterFunc(idleTimeout, func() {
-		clientLock.Lock()
-		defer clientLock.Unlock()
-		if client != nil {
-			_ = client.Disconnect(context.Background())
-			client = nil
-			log.Println("MongoDB connection closed due to inactivity.")
-		}
-	})
 }
 
-// GetBlogTitlesAndSummaries 从 MongoDB 获取所有 Blog 的标题和概述
-func GetBlogInfo() ([]api.BlogResponse, error) {
-	if client == nil {
-		if err := ConnectMongoDB(); err != nil {
-			return nil, err
-		}
+// startQuery 为一次 Mongo 操作创建子 span
+func (m *MongoManager) startQuery(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "mongo."+operation,
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.name", m.cfg.Database),
+		attribute.String("db.collection.name", m.cfg.Collection),
+		attribute.String("db.operation.name", operation),
+	)
+}
+
+// observeQuery 记录一次查询耗时并结束 span；未命中（ErrNoDocuments）不计为错误
+func observeQuery(operation string, span trace.Span, start time.Time, err error) {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = nil
 	}
+	metrics.ObserveMongo(operation, time.Since(start), err)
+	tracing.End(span, err)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// GetBlogInfo 从 MongoDB 获取所有 Blog 的标题和概述
+func (m *MongoManager) GetBlogInfo(ctx context.Context) ([]api.BlogResponse, error) {
+	// 在调用方 ctx 上叠加查询超时：客户端断开或上游截止时间先到都会终止查询
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.QueryTimeout)
 	defer cancel()
 
-	collection := client.Database(databaseName).Collection(collectionName)
+	collection, err := m.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	// 定义查询条件（如果没有条件，可以使用 bson.D{}）
 	filter := bson.D{}
@@ -108,66 +248,111 @@ func GetBlogInfo() ([]api.BlogResponse, error) {
 		{Key: "Title", Value: 1},
 		{Key: "Summary", Value: 1},
 		{Key: "Date", Value: 1},
+		{Key: "DateTZ", Value: 1},
+		{Key: "UpdatedAt", Value: 1},
 	}
 
+	ctx, span := m.startQuery(ctx, "GetBlogInfo")
+	start := time.Now()
 	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(projection))
 	if err != nil {
+		observeQuery("GetBlogInfo", span, start, err)
 		return nil, fmt.Errorf("failed to execute find query: %v", err)
 	}
 	defer cursor.Close(ctx)
 
 	var blogs []api.BlogResponse
 	for cursor.Next(ctx) {
-		var blog api.BlogResponse
-		if err := cursor.Decode(&blog); err != nil {
+		var doc blogDoc
+		if err := cursor.Decode(&doc); err != nil {
+			observeQuery("GetBlogInfo", span, start, err)
 			return nil, fmt.Errorf("failed to decode blog: %v", err)
 		}
-		blogs = append(blogs, blog)
+		blogs = append(blogs, doc.response())
 	}
 
 	if err := cursor.Err(); err != nil {
+		observeQuery("GetBlogInfo", span, start, err)
 		return nil, fmt.Errorf("cursor error: %v", err)
 	}
+	observeQuery("GetBlogInfo", span, start, nil)
 
 	return blogs, nil
 }
 
-func GetLatestBlog() (api.BlogResponse, error) {
-	if client == nil {
-		if err := ConnectMongoDB(); err != nil {
-			return api.BlogResponse{}, err
-		}
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// GetLatestBlog 按日期获取最新一篇博客
+func (m *MongoManager) GetLatestBlog(ctx context.Context) (api.BlogResponse, error) {
+	// 在调用方 ctx 上叠加查询超时：客户端断开或上游截止时间先到都会终止查询
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.QueryTimeout)
 	defer cancel()
 
-	collection := client.Database(databaseName).Collection(collectionName)
+	collection, err := m.collection(ctx)
+	if err != nil {
+		return api.BlogResponse{}, err
+	}
+
+	// 字符串日期按字典序比较会得到错误结果，只要还有未迁移的字符串日期，
+	// 就全量读取后按解析后的日期比较，避免它们被已迁移的文档掩盖
+	ctx, span := m.startQuery(ctx, "GetLatestBlog")
+	start := time.Now()
+	stringDated, err := collection.CountDocuments(ctx,
+		bson.D{{Key: "Date", Value: bson.D{{Key: "$type", Value: "string"}}}},
+		options.Count().SetLimit(1))
+	if err != nil {
+		observeQuery("GetLatestBlog", span, start, err)
+		return api.BlogResponse{}, fmt.Errorf("failed to find latest blog: %w", err)
+	}
+	if stringDated > 0 {
+		observeQuery("GetLatestBlog", span, start, nil)
+		return m.latestByParsedDate(ctx)
+	}
 
-	// 查询条件，按日期降序排列，限制返回1条记录
-	options := options.FindOne().SetSort(bson.D{{Key: "Date", Value: -1}})
+	// 全部为 BSON datetime：按日期降序排列，限制返回1条记录
+	filter := bson.D{{Key: "Date", Value: bson.D{{Key: "$type", Value: "date"}}}}
+	options := options.FindOne().SetSort(bson.D{{Key: "Date", Value: -1}, {Key: "ID", Value: -1}})
 
-	var latestBlog api.BlogResponse
-	err := collection.FindOne(ctx, bson.D{}, options).Decode(&latestBlog)
+	var latest blogDoc
+	err = collection.FindOne(ctx, filter, options).Decode(&latest)
+	observeQuery("GetLatestBlog", span, start, err)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// 没有任何带日期的文档：退化为全量读取后按解析后的日期比较
+		return m.latestByParsedDate(ctx)
+	}
 	if err != nil {
 		return api.BlogResponse{}, fmt.Errorf("failed to find latest blog: %v", err)
 	}
 
-	return latestBlog, nil
+	return latest.response(), nil
 }
 
-// GetBlogContentByID 根据 ID 获取博客内容
-func GetBlogContentByID(id int) (api.BlogContent, error) {
-	if client == nil {
-		if err := ConnectMongoDB(); err != nil {
-			return api.BlogContent{}, err
+// latestByParsedDate 在内存中按解析后的日期选出最新博客
+func (m *MongoManager) latestByParsedDate(ctx context.Context) (api.BlogResponse, error) {
+	blogs, err := m.GetBlogInfo(ctx)
+	if err != nil {
+		return api.BlogResponse{}, err
+	}
+	if len(blogs) == 0 {
+		return api.BlogResponse{}, fmt.Errorf("failed to find latest blog: %v", mongo.ErrNoDocuments)
+	}
+	latest := blogs[0]
+	for _, b := range blogs[1:] {
+		if b.Date.After(latest.Date) || (b.Date.Equal(latest.Date) && b.ID > latest.ID) {
+			latest = b
 		}
 	}
+	return latest, nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// GetBlogContentByID 根据 ID 获取博客内容
+func (m *MongoManager) GetBlogContentByID(ctx context.Context, id int) (api.BlogContent, error) {
+	// 在调用方 ctx 上叠加查询超时：客户端断开或上游截止时间先到都会终止查询
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.QueryTimeout)
 	defer cancel()
 
-	collection := client.Database(databaseName).Collection(collectionName)
+	collection, err := m.collection(ctx)
+	if err != nil {
+		return api.BlogContent{}, err
+	}
 
 	// 查询条件
 	filter := bson.D{{Key: "ID", Value: id}}
@@ -181,25 +366,152 @@ func GetBlogContentByID(id int) (api.BlogContent, error) {
 		Path string `bson:"Path"`
 	}
 
-	err := collection.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&result)
+	ctx, span := m.startQuery(ctx, "GetBlogContentByID")
+	span.SetAttributes(attribute.Int("blog.id", id))
+	start := time.Now()
+	err = collection.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&result)
+	observeQuery("GetBlogContentByID", span, start, err)
+	if err != nil {
+		return notFoundContent(), nil
+	}
+
+	return readBlogContent(m.root, result.ID, result.Path), nil
+}
+
+// ListBlogPaths 列出全部博客的 ID、标题与 markdown 路径
+func (m *MongoManager) ListBlogPaths(ctx context.Context) ([]BlogPath, error) {
+	docs, err := m.allDocs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]BlogPath, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, d.blogPath())
+	}
+	return out, nil
+}
+
+// GetBlogPath 获取单篇博客的 markdown 路径与修改时间
+func (m *MongoManager) GetBlogPath(ctx context.Context, id int) (BlogPath, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.QueryTimeout)
+	defer cancel()
+
+	collection, err := m.collection(ctx)
+	if err != nil {
+		return BlogPath{}, err
+	}
+	projection := bson.D{
+		{Key: "ID", Value: 1},
+		{Key: "Title", Value: 1},
+		{Key: "Path", Value: 1},
+		{Key: "UpdatedAt", Value: 1},
+	}
+
+	var doc blogDoc
+	ctx, span := m.startQuery(ctx, "GetBlogPath")
+	span.SetAttributes(attribute.Int("blog.id", id))
+	start := time.Now()
+	err = collection.FindOne(ctx, bson.D{{Key: "ID", Value: id}}, options.FindOne().SetProjection(projection)).Decode(&doc)
+	observeQuery("GetBlogPath", span, start, err)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return BlogPath{}, ErrBlogNotFound
+	}
+	if err != nil {
+		return BlogPath{}, fmt.Errorf("failed to find blog %d: %v", id, err)
+	}
+	return doc.blogPath(), nil
+}
+
+// allDocs 读取全部博客文档，供迁移到其它存储使用
+func (m *MongoManager) allDocs(ctx context.Context) ([]blogDoc, error) {
+	collection, err := m.collection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := collection.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "ID", Value: 1}}))
 	if err != nil {
-		return api.BlogContent{
-			ID:   404,
-			Text: "博客不存在",
-		}, nil
+		return nil, fmt.Errorf("failed to execute find query: %v", err)
 	}
+	defer cursor.Close(ctx)
+
+	var docs []blogDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode blogs: %v", err)
+	}
+	return docs, nil
+}
 
-	// 读取 markdown 文件内容
-	content, err := os.ReadFile(result.Path)
+// BlogDocuments 按 ID 升序返回全部博客的完整字段
+func (m *MongoManager) BlogDocuments(ctx context.Context) ([]BlogDocument, error) {
+	docs, err := m.allDocs(ctx)
 	if err != nil {
-		return api.BlogContent{
-			ID:   404,
-			Text: "博客不存在",
-		}, nil
+		return nil, err
 	}
+	out := make([]BlogDocument, len(docs))
+	for i, d := range docs {
+		out[i] = d.document()
+	}
+	return out, nil
+}
+
+// SaveBlog 按 ID upsert 博客，只写入非零字段
+func (m *MongoManager) SaveBlog(ctx context.Context, doc BlogDocument) error {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.QueryTimeout)
+	defer cancel()
 
-	return api.BlogContent{
-		ID:   result.ID,
-		Text: string(content),
-	}, nil
+	collection, err := m.collection(ctx)
+	if err != nil {
+		return err
+	}
+	set := bson.D{}
+	add := func(key string, value any, ok bool) {
+		if ok {
+			set = append(set, bson.E{Key: key, Value: value})
+		}
+	}
+	add("Title", doc.Title, doc.Title != "")
+	add("Summary", doc.Summary, doc.Summary != "")
+	add("Slug", doc.Slug, doc.Slug != "")
+	add("Date", doc.Date, !doc.Date.IsZero())
+	add("DateTZ", doc.DateTZ, doc.DateTZ != "")
+	add("UpdatedAt", doc.UpdatedAt, doc.UpdatedAt != nil)
+	add("Path", doc.Path, doc.Path != "")
+
+	ctx, span := m.startQuery(ctx, "SaveBlog")
+	span.SetAttributes(attribute.Int("blog.id", doc.ID))
+	start := time.Now()
+	// upsert 时过滤条件中的 ID 会自动写入新文档
+	if len(set) == 0 {
+		set = bson.D{{Key: "ID", Value: doc.ID}}
+	}
+	_, err = collection.UpdateOne(ctx,
+		bson.D{{Key: "ID", Value: doc.ID}},
+		bson.D{{Key: "$set", Value: set}},
+		options.Update().SetUpsert(true),
+	)
+	observeQuery("SaveBlog", span, start, err)
+	if err != nil {
+		return fmt.Errorf("failed to save blog %d: %v", doc.ID, err)
+	}
+	return nil
+}
+
+// DeleteBlog 按 ID 删除博客
+func (m *MongoManager) DeleteBlog(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.QueryTimeout)
+	defer cancel()
+
+	collection, err := m.collection(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, span := m.startQuery(ctx, "DeleteBlog")
+	span.SetAttributes(attribute.Int("blog.id", id))
+	start := time.Now()
+	_, err = collection.DeleteOne(ctx, bson.D{{Key: "ID", Value: id}})
+	observeQuery("DeleteBlog", span, start, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete blog %d: %v", id, err)
+	}
+	return nil
 }
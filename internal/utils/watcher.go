@@ -0,0 +1,355 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+)
+
+// ContentEventType 内容变更类型
+type ContentEventType string
+
+const (
+	ContentCreated  ContentEventType = "created"
+	ContentModified ContentEventType = "modified"
+	ContentDeleted  ContentEventType = "deleted"
+)
+
+// ContentEvent 一次 markdown 文件变更及其对应的博客
+type ContentEvent struct {
+	Type ContentEventType
+	Path string // 相对内容根目录
+	ID   int
+	Time time.Time
+}
+
+// fileState 用于轮询比较的文件状态
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// ContentWatcher 轮询内容根目录下的 markdown 文件（不依赖 inotify，任何平台可用），
+// 发现新增、修改、删除后重新解析元数据、写回存储、清除正文缓存并广播事件
+type ContentWatcher struct {
+	root     *ContentRoot
+	store    BlogStore
+	writer   BlogWriter
+	cache    *ContentCache // 可为 nil
+	interval time.Duration
+
+	files map[string]fileState // key 为真实路径
+	// missing 上次轮询中已缺失的文件；连续两次缺失才视为删除，
+	// 避免目录短暂不可见（卷重新挂载、目录替换）时误删博客
+	missing map[string]bool
+
+	mu   sync.Mutex
+	subs []chan ContentEvent
+}
+
+// NewContentWatcher 创建监视器；cache 为 nil 时不做缓存失效
+func NewContentWatcher(root *ContentRoot, store BlogStore, writer BlogWriter, cache *ContentCache, interval time.Duration) *ContentWatcher {
+	return &ContentWatcher{
+		root:     root,
+		store:    store,
+		writer:   writer,
+		cache:    cache,
+		interval: interval,
+	}
+}
+
+// Subscribe 订阅变更事件；订阅者处理过慢时事件会被丢弃，不阻塞监视循环
+func (w *ContentWatcher) Subscribe() <-chan ContentEvent {
+	ch := make(chan ContentEvent, 64)
+	w.mu.Lock()
+	w.subs = append(w.subs, ch)
+	w.mu.Unlock()
+	return ch
+}
+
+// Run 阻塞运行直到 ctx 结束。首次扫描只建立基线，不改动存储；
+// 首次扫描失败时由之后第一次成功的轮询建立基线
+func (w *ContentWatcher) Run(ctx context.Context) {
+	files, err := w.scan()
+	if err != nil {
+		log.Printf("[Watch] initial scan failed: %v", err)
+	} else {
+		w.files = files
+	}
+	log.Printf("[Watch] watching %d markdown file(s) under %s every %s", len(files), w.root.Dir(), w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			for _, ch := range w.subs {
+				close(ch)
+			}
+			w.subs = nil
+			w.mu.Unlock()
+			return
+		case <-ticker.C:
+			w.Poll(ctx)
+		}
+	}
+}
+
+// Poll 执行一次扫描并处理差异；尚无基线时本次扫描只作为基线。
+// 扫描出错时跳过本次轮询；文件需在连续两次轮询中缺失才会删除对应博客
+func (w *ContentWatcher) Poll(ctx context.Context) {
+	files, err := w.scan()
+	if err != nil {
+		log.Printf("[Watch] scan failed, skipping poll: %v", err)
+		return
+	}
+	if w.files == nil {
+		w.files = files
+		return
+	}
+
+	var changed []ContentEvent
+	for p, st := range files {
+		old, ok := w.files[p]
+		switch {
+		case !ok:
+			changed = append(changed, ContentEvent{Type: ContentCreated, Path: p})
+		case !old.modTime.Equal(st.modTime) || old.size != st.size:
+			changed = append(changed, ContentEvent{Type: ContentModified, Path: p})
+		}
+	}
+	missing := make(map[string]bool)
+	for p, old := range w.files {
+		if _, ok := files[p]; ok {
+			continue
+		}
+		missing[p] = true
+		if w.missing[p] {
+			changed = append(changed, ContentEvent{Type: ContentDeleted, Path: p})
+		} else {
+			// 首次缺失：保留基线，下次轮询仍缺失才删除
+			files[p] = old
+		}
+	}
+	w.missing = missing
+	if len(changed) == 0 {
+		w.files = files
+		return
+	}
+
+	byPath, maxID, err := w.index(ctx)
+	if err != nil {
+		// 存储暂不可用：保留旧基线，下次轮询重试
+		log.Printf("[Watch] load blog index failed: %v", err)
+		return
+	}
+	for _, ev := range changed {
+		var err error
+		switch ev.Type {
+		case ContentDeleted:
+			ev.ID, err = w.remove(ctx, ev.Path, byPath)
+		default:
+			var id int
+			id, err = w.upsert(ctx, ev.Path, files[ev.Path], byPath, &maxID)
+			ev.ID = id
+		}
+		if errors.Is(err, errIDConflict) {
+			// 文件本身有误，重试无意义：接受其当前状态，文件再次修改时重新处理
+			log.Printf("[Watch] %s %s skipped: %v", ev.Type, ev.Path, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("[Watch] %s %s failed: %v", ev.Type, ev.Path, err)
+			// 保留旧状态，下次轮询重试
+			if old, ok := w.files[ev.Path]; ok {
+				files[ev.Path] = old
+			} else {
+				delete(files, ev.Path)
+			}
+			continue
+		}
+		w.emit(ev)
+	}
+	w.files = files
+}
+
+// scan 列出根目录下全部 .md 文件，跳过隐藏文件与目录。
+// 任何条目不可读（包括根目录本身）都视为扫描失败：不完整的结果会被误判为文件删除
+func (w *ContentWatcher) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(w.root.Dir(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != w.root.Dir() {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(p), ".md") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		files[p] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// index 以真实路径索引现有博客，并返回最大 ID
+func (w *ContentWatcher) index(ctx context.Context) (map[string]BlogPath, int, error) {
+	paths, err := w.store.ListBlogPaths(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	byPath := make(map[string]BlogPath, len(paths))
+	maxID := 0
+	for _, p := range paths {
+		if p.ID > maxID {
+			maxID = p.ID
+		}
+		real := p.Path
+		if !filepath.IsAbs(real) {
+			real = filepath.Join(w.root.Dir(), real)
+		}
+		if r, err := filepath.EvalSymlinks(real); err == nil {
+			real = r
+		}
+		byPath[filepath.Clean(real)] = p
+	}
+	return byPath, maxID, nil
+}
+
+// errIDConflict front matter 中的 ID 已属于另一个文件
+var errIDConflict = errors.New("blog ID already belongs to another file")
+
+// upsert 解析文件元数据并写入存储，返回博客 ID
+func (w *ContentWatcher) upsert(ctx context.Context, p string, st fileState, byPath map[string]BlogPath, maxID *int) (int, error) {
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return 0, err
+	}
+	loc := BlogTimezone()
+	meta := ParseMarkdownMeta(content, loc)
+	updated := st.modTime.UTC()
+	doc := BlogDocument{
+		ID:        meta.ID,
+		Title:     meta.Title,
+		Summary:   meta.Summary,
+		Slug:      meta.Slug,
+		Date:      meta.Date,
+		UpdatedAt: &updated,
+	}
+	if !meta.Date.IsZero() {
+		doc.DateTZ = loc.String()
+	}
+
+	existing, known := byPath[p]
+	// front matter 改了 ID：按新文件写入新 ID，并删除旧 ID 的博客，避免同一文件出现两篇
+	renumbered := known && doc.ID != 0 && doc.ID != existing.ID
+	switch {
+	case known && doc.ID == 0:
+		doc.ID = existing.ID
+	case !known || renumbered:
+		// 新文件：记录相对路径，未指定 ID 时顺延
+		rel, err := w.root.Rel(p)
+		if err != nil {
+			return 0, err
+		}
+		doc.Path = rel
+		if doc.ID == 0 {
+			*maxID++
+			doc.ID = *maxID
+		}
+		if doc.Title == "" {
+			doc.Title = strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+		}
+		if doc.Date.IsZero() {
+			doc.Date = updated
+			doc.DateTZ = loc.String()
+		}
+	}
+	// 显式 ID 不能覆盖另一个文件对应的博客
+	for other, bp := range byPath {
+		if bp.ID == doc.ID && other != p {
+			return 0, fmt.Errorf("%w: ID=%d is used by %s", errIDConflict, doc.ID, other)
+		}
+	}
+	if doc.ID > *maxID {
+		*maxID = doc.ID
+	}
+
+	if renumbered {
+		// 先删旧 ID：写入新 ID 失败时下次轮询会把文件当作新文件重试，不会留下两篇
+		if err := w.writer.DeleteBlog(ctx, existing.ID); err != nil {
+			return 0, err
+		}
+		delete(byPath, p)
+		if w.cache != nil {
+			w.cache.Invalidate(existing.ID)
+		}
+	}
+	if err := w.writer.SaveBlog(ctx, doc); err != nil {
+		return 0, err
+	}
+	byPath[p] = BlogPath{ID: doc.ID, Title: doc.Title, Path: p, UpdatedAt: doc.UpdatedAt}
+	if w.cache != nil {
+		w.cache.Invalidate(doc.ID)
+	}
+	return doc.ID, nil
+}
+
+// remove 删除文件对应的博客；没有对应博客时忽略
+func (w *ContentWatcher) remove(ctx context.Context, p string, byPath map[string]BlogPath) (int, error) {
+	existing, ok := byPath[p]
+	if !ok {
+		return 0, nil
+	}
+	if err := w.writer.DeleteBlog(ctx, existing.ID); err != nil {
+		return 0, err
+	}
+	delete(byPath, p)
+	if w.cache != nil {
+		w.cache.Invalidate(existing.ID)
+	}
+	return existing.ID, nil
+}
+
+// emit 记录并广播事件
+func (w *ContentWatcher) emit(ev ContentEvent) {
+	ev.Time = time.Now()
+	if rel, err := w.root.Rel(ev.Path); err == nil {
+		ev.Path = rel
+	}
+	metrics.ContentWatchEvent(string(ev.Type))
+	log.Printf("[Watch] %s %s (blog ID=%d)", ev.Type, ev.Path, ev.ID)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, ch := range w.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
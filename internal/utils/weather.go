@@ -1,15 +1,20 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
-	"log"
+	"math"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+	"github.com/LtePrince/Personal-Website-backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // IPInfo 描述 IP 定位信息
@@ -21,6 +26,8 @@ type IPInfo struct {
 	CountryCode string  `json:"country_code"`
 	Latitude    float64 `json:"latitude"`
 	Longitude   float64 `json:"longitude"`
+	// Source 给出结果的提供商名称（如 maxmind、ipapi）
+	Source string `json:"-"`
 }
 
 // IsPrivateOrLoopbackIP 粗略判断私有或回环地址
@@ -52,154 +59,129 @@ func IsPrivateOrLoopbackIP(ip string) bool {
 	return false
 }
 
-// ----- IP 地理位置解析 -----
-// 设计目标：
-// 1. 私有 / 回环地址快速返回占位，不发外网请求
-// 2. 主提供商 ipapi.co，有限次重试与日志
-// 3. 失败后使用 ipwho.is 作为后备
-// 4. 代码结构清晰：小函数 + 明确错误语义
-
-// providerRet 统一返回值，用于内部 provider 调用
-type providerRet struct {
-	info      *IPInfo
-	retriable bool // 是否建议上层重试
-	err       error
-	name      string
+// outboundTimeout 单次外部请求的截止时间，叠加在调用方 ctx 之上
+const outboundTimeout = 5 * time.Second
+
+// newOutboundClient 创建带 trace 传播的外部请求客户端；
+// 超时不在 client 上设置，而是由每次请求的 ctx 决定，便于取消向下传递
+func newOutboundClient() *http.Client {
+	return &http.Client{Transport: tracing.Transport(nil)}
 }
 
-// fetchFromIPApi 调用 ipapi.co
-func fetchFromIPApi(ip string, client *http.Client) providerRet {
-	url := fmt.Sprintf("https://ipapi.co/%s/json/", ip)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return providerRet{err: err, retriable: false, name: "ipapi"}
-	}
-	req.Header.Set("User-Agent", "PersonalSite-Geolocate/1.0")
-	req.Header.Set("Accept", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return providerRet{err: err, retriable: true, name: "ipapi"}
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-	if resp.StatusCode != http.StatusOK {
-		// 429/5xx 视为可重试，其它直接失败
-		retriable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
-		return providerRet{err: fmt.Errorf("ipapi status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body))), retriable: retriable, name: "ipapi"}
-	}
-	var info IPInfo
-	if err := json.Unmarshal(body, &info); err != nil {
-		return providerRet{err: err, retriable: false, name: "ipapi"}
-	}
-	log.Printf("[IPGeo] ipapi success ip=%s city=%s region=%s countryCode=%s lat=%.4f lon=%.4f", ip, info.City, info.Region, info.CountryCode, info.Latitude, info.Longitude)
-	// 至少 city 或 countryCode 有值才认为有效
-	if info.City == "" && info.CountryCode == "" {
-		return providerRet{err: errors.New("ipapi empty essential fields"), retriable: false, name: "ipapi"}
+// sleepCtx 等待 d 或 ctx 结束，ctx 先结束时返回其错误
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
-	return providerRet{info: &info, name: "ipapi"}
 }
 
-// fetchFromIPWhoIs 调用 ipwho.is
-func fetchFromIPWhoIs(ip string, client *http.Client) providerRet {
-	url := fmt.Sprintf("https://ipwho.is/%s", ip)
-	resp, err := client.Get(url)
-	if err != nil {
-		return providerRet{err: err, name: "ipwhois"}
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-	if resp.StatusCode != http.StatusOK {
-		return providerRet{err: fmt.Errorf("ipwho.is status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body))), name: "ipwhois"}
-	}
-	var raw map[string]any
-	if err := json.Unmarshal(body, &raw); err != nil {
-		return providerRet{err: err, name: "ipwhois"}
-	}
-	info := &IPInfo{IP: ip}
-	if v, ok := raw["city"].(string); ok {
-		info.City = v
-	}
-	if v, ok := raw["region"].(string); ok {
-		info.Region = v
-	}
-	if v, ok := raw["country_code"].(string); ok {
-		info.CountryCode = v
-	}
-	if v, ok := raw["country"].(string); ok {
-		info.Country = v
-	}
-	if v, ok := raw["latitude"].(float64); ok {
-		info.Latitude = v
-	}
-	if v, ok := raw["longitude"].(float64); ok {
-		info.Longitude = v
-	}
-	log.Printf("[IPGeo] ipwho.is success ip=%s city=%s region=%s countryCode=%s lat=%.4f lon=%.4f", ip, info.City, info.Region, info.CountryCode, info.Latitude, info.Longitude)
-	if info.City == "" && info.CountryCode == "" {
-		return providerRet{err: errors.New("ipwho.is empty essential fields"), name: "ipwhois"}
-	}
-	return providerRet{info: info, name: "ipwhois"}
+// 天气数据源名称，同时用于指标标签与 SourceError
+const (
+	SourceForecast   = "open-meteo"
+	SourceAirQuality = "open-meteo-aqi"
+)
+
+// openMeteoForecastURL / openMeteoAirQualityURL 可通过环境变量指向自建实例或测试替身
+func openMeteoForecastURL() string {
+	return getenv("OPEN_METEO_FORECAST_URL", "https://api.open-meteo.com/v1/forecast")
 }
 
-// LookupIPLocation 统一对外调用：
-//   - 私有/回环: 立即返回占位
-//   - 主提供商 ipapi 重试 2 次
-//   - 失败后 fallback ipwho.is
-//   - 返回 IPInfo 或错误
-func LookupIPLocation(ip string) (*IPInfo, error) {
-	ip = strings.TrimSpace(ip)
-	if ip == "" {
-		return nil, errors.New("empty ip")
-	}
-	if IsPrivateOrLoopbackIP(ip) {
-		// 私有地址：不请求外部服务
-		info := &IPInfo{IP: ip, City: "Local Network", Region: "", Country: "", CountryCode: "", Latitude: 0, Longitude: 0}
-		return info, nil
-	}
-	client := &http.Client{Timeout: 5 * time.Second}
-
-	// 主提供商重试
-	var lastErr error
-	for attempt := 0; attempt < 2; attempt++ {
-		ret := fetchFromIPApi(ip, client)
-		if ret.info != nil {
-			return ret.info, nil
-		}
-		lastErr = ret.err
-		log.Printf("[IPGeo] ipapi attempt %d error: %v (retriable=%v)", attempt+1, ret.err, ret.retriable)
-		if !ret.retriable {
-			break
-		}
-		// 退避
-		time.Sleep(time.Duration(200*(attempt+1)) * time.Millisecond)
-	}
+func openMeteoAirQualityURL() string {
+	return getenv("OPEN_METEO_AIR_QUALITY_URL", "https://air-quality-api.open-meteo.com/v1/air-quality")
+}
+
+// SourceError 单个天气数据源的失败
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return e.Source + ": " + e.Err.Error()
+}
 
-	// 后备
-	fb := fetchFromIPWhoIs(ip, client)
-	if fb.info != nil {
-		return fb.info, nil
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// WeatherError 所有数据源均失败，没有任何可用数据
+type WeatherError struct {
+	Errors []*SourceError
+}
+
+func (e *WeatherError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, se := range e.Errors {
+		msgs[i] = se.Error()
 	}
-	if lastErr != nil {
-		return nil, lastErr
+	return "all weather sources failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *WeatherError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, se := range e.Errors {
+		errs[i] = se
 	}
-	return nil, fb.err
+	return errs
 }
 
 // WeatherAQI 描述天气与空气质量（部分字段可为空）。
 // 数值字段保持与外部 API 一致的单位：
 //
-//	TempC: 摄氏度
-//	WindSpeedKmh: 公里/小时
-//	Humidity: 相对湿度百分比
+//	TempC / ApparentTempC: 摄氏度
+//	WindSpeedKmh / WindGustsKmh: 公里/小时
+//	WindDirectionDeg: 风的来向，0~360 度，0 为正北
+//	Humidity / CloudCover: 百分比
 //	AQIUS: 美国 AQI 指标
+//	UVIndex: 紫外线指数
+//	PressureHPa: 海平面气压，百帕
+//	PrecipitationMm: 当前时段降水量，毫米
 //
 // WeatherText: 经过 WeatherCodeToText 映射后的中文描述
 type WeatherAQI struct {
-	TempC        *float64
-	WindSpeedKmh *float64
-	Humidity     *float64
-	AQIUS        *float64
-	WeatherText  string
+	TempC            *float64
+	ApparentTempC    *float64
+	WindSpeedKmh     *float64
+	WindGustsKmh     *float64
+	WindDirectionDeg *float64
+	Humidity         *float64
+	CloudCover       *float64
+	PressureHPa      *float64
+	PrecipitationMm  *float64
+	UVIndex          *float64
+	AQIUS            *float64
+	// IsDay 该地当前是否为白天
+	IsDay       *bool
+	WeatherText string
+	// WeatherCode 原始 WMO weather code，供按语言生成描述
+	WeatherCode *int
+	// FetchedAt 数据从 Open-Meteo 获取的时间，经缓存返回时可能早于当前时间
+	FetchedAt time.Time
+	// Errors 失败的数据源；非空时对应字段缺失，结果为部分数据
+	Errors []*SourceError
+}
+
+// Degraded 是否有数据源失败
+func (w *WeatherAQI) Degraded() bool {
+	return len(w.Errors) > 0
+}
+
+// FailedSources 失败的数据源名称
+func (w *WeatherAQI) FailedSources() []string {
+	return sourceNames(w.Errors)
+}
+
+func sourceNames(errs []*SourceError) []string {
+	var names []string
+	for _, e := range errs {
+		names = append(names, e.Source)
+	}
+	return names
 }
 
 // WeatherCodeToText 将 Open‑Meteo weather_code 映射为中文描述。
@@ -227,26 +209,131 @@ func WeatherCodeToText(code int) string {
 	}
 }
 
-// FetchWeatherAndAQI 使用 Open-Meteo 获取当前天气与 US AQI
-func FetchWeatherAndAQI(lat, lon float64) (*WeatherAQI, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
+// compassPoints 16 方位，从正北顺时针
+var compassPoints = [16]string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// CompassPoint 将风向角度（0 为正北，顺时针）转换为 16 方位缩写，如 NNE
+func CompassPoint(deg float64) string {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return compassPoints[int(math.Round(deg/22.5))%16]
+}
+
+// WeatherIcon 由 weather code 与昼夜得到稳定的图标标识，供前端选择图标；
+// 只有晴、少云、多云及阵性降水区分昼夜（-day / -night）。isDay 为 nil 时按白天处理
+func WeatherIcon(code *int, isDay *bool) string {
+	if code == nil {
+		return "unknown"
+	}
+	suffix := "-day"
+	if isDay != nil && !*isDay {
+		suffix = "-night"
+	}
+	switch *code {
+	case 0:
+		return "clear" + suffix
+	case 1:
+		return "mostly-clear" + suffix
+	case 2:
+		return "partly-cloudy" + suffix
+	case 3:
+		return "overcast"
+	case 45, 48:
+		return "fog"
+	case 51, 53, 55:
+		return "drizzle"
+	case 56, 57:
+		return "freezing-drizzle"
+	case 61, 63:
+		return "rain"
+	case 65:
+		return "heavy-rain"
+	case 66, 67:
+		return "freezing-rain"
+	case 71, 73:
+		return "snow"
+	case 75:
+		return "heavy-snow"
+	case 77:
+		return "snow-grains"
+	case 80, 81, 82:
+		return "rain-showers" + suffix
+	case 85, 86:
+		return "snow-showers" + suffix
+	case 95:
+		return "thunderstorm"
+	case 96, 99:
+		return "thunderstorm-hail"
+	default:
+		return "unknown"
+	}
+}
+
+// fetchOpenMeteo 执行 GET 并解析 JSON 到 v，结果计入指标与 trace
+func fetchOpenMeteo(ctx context.Context, client *http.Client, source, url string, v any) (err error) {
+	ctx, span := tracing.Start(ctx, "weather.fetch", attribute.String("weather.provider", source))
+	defer func() {
+		tracing.End(span, err)
+		if err == nil {
+			metrics.UpstreamResult(source, metrics.OutcomeSuccess)
+		} else {
+			metrics.UpstreamResult(source, metrics.OutcomeFailure)
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// FetchWeatherAndAQI 使用 Open-Meteo 并发获取当前天气与 US AQI，两个请求共享
+// 在 ctx 上叠加的 outboundTimeout。单个数据源失败时返回部分数据并在 Errors 中注明；
+// 全部失败时返回 *WeatherError
+func FetchWeatherAndAQI(ctx context.Context, lat, lon float64) (*WeatherAQI, error) {
+	client := newOutboundClient()
 
 	// 构造请求 URL：仅拉取当前需要用到的字段，减小响应体
 	wURL := fmt.Sprintf(
-		"https://api.open-meteo.com/v1/forecast?latitude=%f&longitude=%f&current=temperature_2m,relative_humidity_2m,wind_speed_10m,weather_code&timezone=auto",
-		lat, lon,
+		"%s?latitude=%f&longitude=%f&current=temperature_2m,apparent_temperature,relative_humidity_2m,"+
+			"wind_speed_10m,wind_direction_10m,wind_gusts_10m,weather_code,is_day,uv_index,pressure_msl,precipitation,cloud_cover&timezone=auto",
+		openMeteoForecastURL(), lat, lon,
 	)
 	aqiURL := fmt.Sprintf(
-		"https://air-quality-api.open-meteo.com/v1/air-quality?latitude=%f&longitude=%f&current=us_aqi",
-		lat, lon,
+		"%s?latitude=%f&longitude=%f&current=us_aqi",
+		openMeteoAirQualityURL(), lat, lon,
 	)
 
 	type wResp struct {
 		Current struct {
-			Temperature2M      *float64 `json:"temperature_2m"`
-			RelativeHumidity2M *float64 `json:"relative_humidity_2m"`
-			WindSpeed10M       *float64 `json:"wind_speed_10m"`
-			WeatherCode        *int     `json:"weather_code"`
+			Temperature2M       *float64 `json:"temperature_2m"`
+			ApparentTemperature *float64 `json:"apparent_temperature"`
+			RelativeHumidity2M  *float64 `json:"relative_humidity_2m"`
+			WindSpeed10M        *float64 `json:"wind_speed_10m"`
+			WindDirection10M    *float64 `json:"wind_direction_10m"`
+			WindGusts10M        *float64 `json:"wind_gusts_10m"`
+			WeatherCode         *int     `json:"weather_code"`
+			IsDay               *int     `json:"is_day"`
+			UVIndex             *float64 `json:"uv_index"`
+			PressureMSL         *float64 `json:"pressure_msl"`
+			Precipitation       *float64 `json:"precipitation"`
+			CloudCover          *float64 `json:"cloud_cover"`
 		} `json:"current"`
 	}
 	type aqiResp struct {
@@ -256,35 +343,59 @@ func FetchWeatherAndAQI(lat, lon float64) (*WeatherAQI, error) {
 	}
 
 	var (
-		wData wResp
-		aData aqiResp
+		wData      wResp
+		aData      aqiResp
+		wErr, aErr error
+		wg         sync.WaitGroup
 	)
+	fetchCtx, cancel := context.WithTimeout(ctx, outboundTimeout)
+	defer cancel()
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		wErr = fetchOpenMeteo(fetchCtx, client, SourceForecast, wURL, &wData)
+	}()
+	go func() {
+		defer wg.Done()
+		aErr = fetchOpenMeteo(fetchCtx, client, SourceAirQuality, aqiURL, &aData)
+	}()
+	wg.Wait()
+	// 调用方已取消：结果不完整，不再当作正常数据返回
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	// helper：执行 GET 并在 200 时解 JSON，不抛错（容忍失败）
-	fetchJSON := func(url string, v any) {
-		if resp, err := client.Get(url); err == nil {
-			defer resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				if b, err := io.ReadAll(resp.Body); err == nil {
-					_ = json.Unmarshal(b, v)
-				}
-			}
+	res := &WeatherAQI{WeatherText: "天气", FetchedAt: time.Now()}
+	if wErr == nil {
+		cur := wData.Current
+		res.TempC = cur.Temperature2M
+		res.ApparentTempC = cur.ApparentTemperature
+		res.WindSpeedKmh = cur.WindSpeed10M
+		res.WindGustsKmh = cur.WindGusts10M
+		res.WindDirectionDeg = cur.WindDirection10M
+		res.Humidity = cur.RelativeHumidity2M
+		res.CloudCover = cur.CloudCover
+		res.PressureHPa = cur.PressureMSL
+		res.PrecipitationMm = cur.Precipitation
+		res.UVIndex = cur.UVIndex
+		if cur.IsDay != nil {
+			isDay := *cur.IsDay == 1
+			res.IsDay = &isDay
 		}
+		res.WeatherCode = wData.Current.WeatherCode
+		if wData.Current.WeatherCode != nil {
+			res.WeatherText = WeatherCodeToText(*wData.Current.WeatherCode)
+		}
+	} else {
+		res.Errors = append(res.Errors, &SourceError{Source: SourceForecast, Err: wErr})
 	}
-
-	fetchJSON(wURL, &wData)
-	fetchJSON(aqiURL, &aData)
-
-	weatherText := "天气"
-	if wData.Current.WeatherCode != nil {
-		weatherText = WeatherCodeToText(*wData.Current.WeatherCode)
+	if aErr == nil {
+		res.AQIUS = aData.Current.USAqi
+	} else {
+		res.Errors = append(res.Errors, &SourceError{Source: SourceAirQuality, Err: aErr})
 	}
-
-	return &WeatherAQI{
-		TempC:        wData.Current.Temperature2M,
-		WindSpeedKmh: wData.Current.WindSpeed10M,
-		Humidity:     wData.Current.RelativeHumidity2M,
-		AQIUS:        aData.Current.USAqi,
-		WeatherText:  weatherText,
-	}, nil
+	if len(res.Errors) == 2 {
+		return nil, &WeatherError{Errors: res.Errors}
+	}
+	return res, nil
 }
@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// WeatherCacheConfig 天气缓存配置
+type WeatherCacheConfig struct {
+	// Grid 坐标取整的网格步长（度），同一网格内的访客共享一份数据；0.1° 约 11 km
+	Grid float64
+	// TTL 新鲜期，期间直接返回缓存
+	TTL time.Duration
+	// MaxStale 超过 TTL 后仍可返回旧数据的最长时间：期间先返回旧数据并在后台刷新，
+	// 上游故障时也继续使用旧数据；超过后必须同步获取
+	MaxStale time.Duration
+	// MaxEntries 网格条目上限，超出时按最近最少使用淘汰；<=0 关闭缓存
+	MaxEntries int
+	// DegradedTTL 部分数据源失败时结果的新鲜期（不超过 TTL），期间不再请求上游，
+	// 避免一个数据源故障时每个请求都打到上游
+	DegradedTTL time.Duration
+}
+
+// WeatherCacheConfigFromEnv 读取 WEATHER_CACHE_GRID / WEATHER_CACHE_TTL / WEATHER_CACHE_MAX_STALE /
+// WEATHER_CACHE_SIZE / WEATHER_CACHE_DEGRADED_TTL
+func WeatherCacheConfigFromEnv() WeatherCacheConfig {
+	grid, err := strconv.ParseFloat(getenv("WEATHER_CACHE_GRID", "0.1"), 64)
+	if err != nil || grid <= 0 {
+		grid = 0.1
+	}
+	return WeatherCacheConfig{
+		Grid:        grid,
+		TTL:         envDuration("WEATHER_CACHE_TTL", "10m"),
+		MaxStale:    envDuration("WEATHER_CACHE_MAX_STALE", "6h"),
+		MaxEntries:  envInt("WEATHER_CACHE_SIZE", 1000),
+		DegradedTTL: envDuration("WEATHER_CACHE_DEGRADED_TTL", "1m"),
+	}
+}
+
+// gridKey 网格索引
+type gridKey struct{ lat, lon int64 }
+
+func (k gridKey) String() string {
+	return strconv.FormatInt(k.lat, 10) + "," + strconv.FormatInt(k.lon, 10)
+}
+
+type gridEntry[T any] struct {
+	key       gridKey
+	val       T
+	fetchedAt time.Time
+	// degraded 本次获取有数据源失败，新鲜期为 DegradedTTL
+	degraded bool
+	// completeAt 值中最旧部分的获取时间：完整获取时等于 fetchedAt，合并了旧数据时为旧数据的时间
+	completeAt time.Time
+}
+
+// gridCache 按取整坐标缓存 fetch 的结果，支持 stale-while-revalidate
+type gridCache[T any] struct {
+	name  string
+	cfg   WeatherCacheConfig
+	fetch func(ctx context.Context, lat, lon float64) (T, error)
+	// complete 为 false 的结果（部分数据源失败）只在 DegradedTTL 内视为新鲜；nil 表示总是完整
+	complete func(T) bool
+	// merge 用 prev 补齐 next 中失败数据源的字段；nil 表示不合并
+	merge func(prev, next T) T
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[gridKey]*list.Element
+
+	hits, misses, evictions atomic.Uint64
+
+	group singleflight.Group
+}
+
+func newGridCache[T any](name string, cfg WeatherCacheConfig, fetch func(ctx context.Context, lat, lon float64) (T, error)) *gridCache[T] {
+	return &gridCache[T]{
+		name:  name,
+		cfg:   cfg,
+		fetch: fetch,
+		ll:    list.New(),
+		items: make(map[gridKey]*list.Element),
+	}
+}
+
+// snap 将坐标归到网格中心
+func (c *gridCache[T]) snap(lat, lon float64) (gridKey, float64, float64) {
+	k := gridKey{int64(math.Round(lat / c.cfg.Grid)), int64(math.Round(lon / c.cfg.Grid))}
+	return k, float64(k.lat) * c.cfg.Grid, float64(k.lon) * c.cfg.Grid
+}
+
+// Get 返回网格数据及其获取时间
+func (c *gridCache[T]) Get(ctx context.Context, lat, lon float64) (T, time.Time, error) {
+	if c.cfg.MaxEntries <= 0 {
+		v, err := c.fetch(ctx, lat, lon)
+		return v, time.Now(), err
+	}
+	key, glat, glon := c.snap(lat, lon)
+	if e, ok := c.get(key); ok {
+		age := time.Since(e.fetchedAt)
+		ttl := c.cfg.TTL
+		if e.degraded {
+			ttl = min(c.cfg.DegradedTTL, c.cfg.TTL)
+		}
+		switch {
+		case age < ttl:
+			c.hits.Add(1)
+			return e.val, e.fetchedAt, nil
+		case age < c.cfg.TTL+c.cfg.MaxStale:
+			// 旧数据先返回，后台刷新；刷新失败时保留旧数据
+			c.hits.Add(1)
+			c.group.DoChan(key.String(), func() (any, error) {
+				return c.refresh(context.WithoutCancel(ctx), key, glat, glon)
+			})
+			return e.val, e.fetchedAt, nil
+		}
+	}
+	c.misses.Add(1)
+
+	ch := c.group.DoChan(key.String(), func() (any, error) {
+		return c.refresh(context.WithoutCancel(ctx), key, glat, glon)
+	})
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, time.Time{}, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			var zero T
+			return zero, time.Time{}, res.Err
+		}
+		e := res.Val.(*gridEntry[T])
+		return e.val, e.fetchedAt, nil
+	}
+}
+
+// refresh 获取并写入缓存；调用方已与请求取消解耦，这里叠加一个总超时
+func (c *gridCache[T]) refresh(ctx context.Context, key gridKey, lat, lon float64) (*gridEntry[T], error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*outboundTimeout)
+	defer cancel()
+	v, err := c.fetch(ctx, lat, lon)
+	if err != nil {
+		log.Printf("[Weather] %s refresh %.2f,%.2f failed: %v", c.name, lat, lon, err)
+		return nil, err
+	}
+	now := time.Now()
+	e := &gridEntry[T]{key: key, val: v, fetchedAt: now, completeAt: now}
+	if c.complete != nil && !c.complete(v) {
+		// 部分数据源失败：在最大陈旧时间内沿用上一份数据中对应的部分，而不是整体替换
+		e.degraded = true
+		if old, ok := c.get(key); ok && c.merge != nil && now.Sub(old.completeAt) < c.cfg.TTL+c.cfg.MaxStale {
+			e.val = c.merge(old.val, v)
+			e.completeAt = old.completeAt
+		}
+	}
+	c.put(e)
+	return e, nil
+}
+
+func (c *gridCache[T]) get(key gridKey) (*gridEntry[T], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*gridEntry[T]), true
+}
+
+func (c *gridCache[T]) put(e *gridEntry[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[e.key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[e.key] = c.ll.PushFront(e)
+	for c.ll.Len() > c.cfg.MaxEntries {
+		back := c.ll.Back()
+		c.ll.Remove(back)
+		delete(c.items, back.Value.(*gridEntry[T]).key)
+		c.evictions.Add(1)
+	}
+}
+
+func (c *gridCache[T]) stats() metrics.CacheStats {
+	c.mu.Lock()
+	entries := c.ll.Len()
+	c.mu.Unlock()
+	return metrics.CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+// WeatherCache 天气与空气质量、预报的网格缓存，两者共用同一配置
+type WeatherCache struct {
+	current  *gridCache[*WeatherAQI]
+	forecast *gridCache[*Forecast]
+}
+
+// NewWeatherCache 创建缓存；fetch 为 nil 时使用 FetchWeatherAndAQI，预报固定使用 FetchForecast
+func NewWeatherCache(cfg WeatherCacheConfig, fetch func(ctx context.Context, lat, lon float64) (*WeatherAQI, error)) *WeatherCache {
+	if fetch == nil {
+		fetch = FetchWeatherAndAQI
+	}
+	current := newGridCache("current", cfg, fetch)
+	current.complete = func(w *WeatherAQI) bool { return !w.Degraded() }
+	current.merge = mergeWeatherAQI
+	return &WeatherCache{current: current, forecast: newGridCache("forecast", cfg, FetchForecast)}
+}
+
+// mergeWeatherAQI 用 prev 中成功获取的数据补齐 next 中失败的数据源
+func mergeWeatherAQI(prev, next *WeatherAQI) *WeatherAQI {
+	failed := make(map[string]bool, len(prev.Errors))
+	for _, e := range prev.Errors {
+		failed[e.Source] = true
+	}
+	out := *next
+	out.Errors = nil
+	for _, e := range next.Errors {
+		switch {
+		case failed[e.Source]:
+			out.Errors = append(out.Errors, e)
+		case e.Source == SourceForecast:
+			// 天气字段整体取自 prev，只保留本次获取的空气质量
+			aqi, kept := out.AQIUS, out.Errors
+			out = *prev
+			out.AQIUS, out.FetchedAt, out.Errors = aqi, next.FetchedAt, kept
+		case e.Source == SourceAirQuality:
+			out.AQIUS = prev.AQIUS
+		default:
+			out.Errors = append(out.Errors, e)
+		}
+	}
+	return &out
+}
+
+// Current 返回当前天气；结果为共享数据，调用方不应修改。FetchedAt 为数据实际获取时间
+func (c *WeatherCache) Current(ctx context.Context, lat, lon float64) (*WeatherAQI, error) {
+	v, fetchedAt, err := c.current.Get(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	cp := *v
+	cp.FetchedAt = fetchedAt
+	return &cp, nil
+}
+
+// Forecast 返回逐小时与逐日预报；结果为共享数据，调用方不应修改。
+// 缓存期内数据会“变旧”，调用方应使用 NextHours / DaysFrom 按当前时间截取
+func (c *WeatherCache) Forecast(ctx context.Context, lat, lon float64) (*Forecast, error) {
+	v, fetchedAt, err := c.forecast.Get(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	cp := *v
+	cp.FetchedAt = fetchedAt
+	return &cp, nil
+}
+
+// Stats 返回缓存统计快照（当前天气与预报合计）；条目未按字节计量，Bytes 为 0
+func (c *WeatherCache) Stats() metrics.CacheStats {
+	cur, fc := c.current.stats(), c.forecast.stats()
+	return metrics.CacheStats{
+		Hits:      cur.Hits + fc.Hits,
+		Misses:    cur.Misses + fc.Misses,
+		Evictions: cur.Evictions + fc.Evictions,
+		Entries:   cur.Entries + fc.Entries,
+	}
+}
+
+var (
+	defaultWeatherCacheOnce sync.Once
+	defaultWeatherCache     *WeatherCache
+)
+
+// DefaultWeatherCache 按环境变量配置的进程级天气缓存
+func DefaultWeatherCache() *WeatherCache {
+	defaultWeatherCacheOnce.Do(func() {
+		defaultWeatherCache = NewWeatherCache(WeatherCacheConfigFromEnv(), nil)
+	})
+	return defaultWeatherCache
+}
@@ -0,0 +1,165 @@
+package utils
+
+import "strings"
+
+// 支持的天气描述语言
+const (
+	LangZH = "zh"
+	LangEN = "en"
+)
+
+// ParseLang 将语言标签（如 zh-CN、en-US、EN）归一为支持的语言，不支持时 ok 为 false
+func ParseLang(tag string) (lang string, ok bool) {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	switch primary {
+	case LangZH, LangEN:
+		return primary, true
+	}
+	return "", false
+}
+
+// weatherDescriptions WMO weather code 的完整描述，参考 https://open-meteo.com/en/docs
+var weatherDescriptions = map[int][2]string{ // {zh, en}
+	0:  {"晴", "Clear sky"},
+	1:  {"晴间多云", "Mainly clear"},
+	2:  {"多云", "Partly cloudy"},
+	3:  {"阴", "Overcast"},
+	45: {"雾", "Fog"},
+	48: {"冻雾", "Depositing rime fog"},
+	51: {"小毛毛雨", "Light drizzle"},
+	53: {"毛毛雨", "Moderate drizzle"},
+	55: {"大毛毛雨", "Dense drizzle"},
+	56: {"小冻毛毛雨", "Light freezing drizzle"},
+	57: {"大冻毛毛雨", "Dense freezing drizzle"},
+	61: {"小雨", "Slight rain"},
+	63: {"中雨", "Moderate rain"},
+	65: {"大雨", "Heavy rain"},
+	66: {"小冻雨", "Light freezing rain"},
+	67: {"大冻雨", "Heavy freezing rain"},
+	71: {"小雪", "Slight snowfall"},
+	73: {"中雪", "Moderate snowfall"},
+	75: {"大雪", "Heavy snowfall"},
+	77: {"米雪", "Snow grains"},
+	80: {"小阵雨", "Slight rain showers"},
+	81: {"中阵雨", "Moderate rain showers"},
+	82: {"强阵雨", "Violent rain showers"},
+	85: {"小阵雪", "Slight snow showers"},
+	86: {"大阵雪", "Heavy snow showers"},
+	95: {"雷阵雨", "Thunderstorm"},
+	96: {"雷阵雨伴有小冰雹", "Thunderstorm with slight hail"},
+	99: {"雷阵雨伴有大冰雹", "Thunderstorm with heavy hail"},
+}
+
+// WeatherDescription 返回 weather code 在指定语言下的描述；code 为 nil 或未知时返回占位描述。
+// 与 WeatherCodeToText 的粗分类不同，这里逐个区分强度
+func WeatherDescription(code *int, lang string) string {
+	idx := 0
+	if lang == LangEN {
+		idx = 1
+	}
+	if code != nil {
+		if d, ok := weatherDescriptions[*code]; ok {
+			return d[idx]
+		}
+	}
+	return [2]string{"天气", "Weather"}[idx]
+}
+
+// compassNamesZH 16 方位的中文名称，与 CompassPoint 的缩写一一对应
+var compassNamesZH = map[string]string{
+	"N": "北", "NNE": "北东北", "NE": "东北", "ENE": "东东北",
+	"E": "东", "ESE": "东东南", "SE": "东南", "SSE": "南东南",
+	"S": "南", "SSW": "南西南", "SW": "西南", "WSW": "西西南",
+	"W": "西", "WNW": "西西北", "NW": "西北", "NNW": "北西北",
+}
+
+// CompassText 方位缩写在指定语言下的展示文本；英文直接使用缩写
+func CompassText(point, lang string) string {
+	if lang == LangZH {
+		if name, ok := compassNamesZH[point]; ok {
+			return name
+		}
+	}
+	return point
+}
+
+// UnitSystem 响应使用的单位制；上游数据始终为公制
+type UnitSystem string
+
+const (
+	UnitsMetric   UnitSystem = "metric"
+	UnitsImperial UnitSystem = "imperial"
+)
+
+// ParseUnitSystem 解析 units 参数（不区分大小写），不支持时 ok 为 false
+func ParseUnitSystem(s string) (u UnitSystem, ok bool) {
+	switch u := UnitSystem(strings.ToLower(strings.TrimSpace(s))); u {
+	case UnitsMetric, UnitsImperial:
+		return u, true
+	}
+	return "", false
+}
+
+// Temperature 将摄氏度转换为该单位制
+func (u UnitSystem) Temperature(c float64) float64 {
+	if u == UnitsImperial {
+		return c*9/5 + 32
+	}
+	return c
+}
+
+// Speed 将公里/小时转换为该单位制
+func (u UnitSystem) Speed(kmh float64) float64 {
+	if u == UnitsImperial {
+		return kmh / 1.609344
+	}
+	return kmh
+}
+
+// TemperatureLabel 温度单位
+func (u UnitSystem) TemperatureLabel() string {
+	if u == UnitsImperial {
+		return "°F"
+	}
+	return "°C"
+}
+
+// SpeedLabel 风速单位
+func (u UnitSystem) SpeedLabel() string {
+	if u == UnitsImperial {
+		return "mph"
+	}
+	return "km/h"
+}
+
+// Pressure 将百帕转换为该单位制（英制为英寸汞柱）
+func (u UnitSystem) Pressure(hPa float64) float64 {
+	if u == UnitsImperial {
+		return hPa * 0.02952998
+	}
+	return hPa
+}
+
+// Precipitation 将毫米转换为该单位制（英制为英寸）
+func (u UnitSystem) Precipitation(mm float64) float64 {
+	if u == UnitsImperial {
+		return mm / 25.4
+	}
+	return mm
+}
+
+// PressureLabel 气压单位
+func (u UnitSystem) PressureLabel() string {
+	if u == UnitsImperial {
+		return "inHg"
+	}
+	return "hPa"
+}
+
+// PrecipitationLabel 降水量单位
+func (u UnitSystem) PrecipitationLabel() string {
+	if u == UnitsImperial {
+		return "in"
+	}
+	return "mm"
+}
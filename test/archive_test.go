@@ -0,0 +1,321 @@
+package test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/archive"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeCollection 内存中的博客集合，满足 archive.Collection
+type fakeCollection struct {
+	mu   sync.Mutex
+	docs []bson.D
+	// replaced 记录 ReplaceOne 写入的文档，按 ID 索引
+	replaced map[int32]bson.D
+}
+
+func (c *fakeCollection) Find(context.Context, interface{}, ...*options.FindOptions) (*mongo.Cursor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	docs := make([]interface{}, len(c.docs))
+	for i, d := range c.docs {
+		docs[i] = d
+	}
+	return mongo.NewCursorFromDocuments(docs, nil, nil)
+}
+
+func (c *fakeCollection) ReplaceOne(_ context.Context, filter interface{}, replacement interface{}, _ ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.replaced == nil {
+		c.replaced = make(map[int32]bson.D)
+	}
+	var id int32
+	for _, e := range filter.(bson.D) {
+		if e.Key == "ID" {
+			switch v := e.Value.(type) {
+			case int:
+				id = int32(v)
+			case int32:
+				id = v
+			}
+		}
+	}
+	c.replaced[id] = replacement.(bson.D)
+	return &mongo.UpdateResult{UpsertedCount: 1}, nil
+}
+
+// field 读取文档字段
+func field(doc bson.D, key string) any {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+// archiveFixture 准备内容根目录、静态目录与集合并导出归档
+func archiveFixture(t *testing.T) (root *utils.ContentRoot, staticDir string, coll *fakeCollection, data []byte) {
+	t.Helper()
+	contentDir := t.TempDir()
+	staticDir = t.TempDir()
+	root, err := utils.NewContentRoot(contentDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(root.Dir(), "posts"), 0o755)
+	os.WriteFile(filepath.Join(root.Dir(), "posts", "hello.md"), []byte("# Hello\n"), 0o644)
+	os.WriteFile(filepath.Join(root.Dir(), "posts", "rel.md"), []byte("# Relative\n"), 0o644)
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(secret, []byte("top secret"), 0o644)
+	os.MkdirAll(filepath.Join(staticDir, "img"), 0o755)
+	os.WriteFile(filepath.Join(staticDir, "img", "a.png"), []byte("png-bytes"), 0o644)
+
+	coll = &fakeCollection{docs: []bson.D{
+		{{Key: "ID", Value: int32(1)}, {Key: "Title", Value: "Hello"}, {Key: "Path", Value: filepath.Join(root.Dir(), "posts", "hello.md")}},
+		{{Key: "ID", Value: int32(2)}, {Key: "Title", Value: "No file"}},
+		// 监视器写入的相对路径、越出内容根目录的路径与已丢失的文件
+		{{Key: "ID", Value: int32(3)}, {Key: "Title", Value: "Relative"}, {Key: "Path", Value: "posts/rel.md"}},
+		{{Key: "ID", Value: int32(4)}, {Key: "Title", Value: "Escape"}, {Key: "Path", Value: secret}},
+		{{Key: "ID", Value: int32(5)}, {Key: "Title", Value: "Gone"}, {Key: "Path", Value: "posts/gone.md"}},
+	}}
+	var buf bytes.Buffer
+	m, err := archive.Export(context.Background(), coll, &buf, archive.ExportOptions{StaticDir: staticDir, ContentRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Posts) != 5 || len(m.Files) != 4 {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+	for _, p := range m.Posts {
+		failed := p.ID == 4 || p.ID == 5
+		if (p.ContentError != "") != failed || (p.Content != "") != (p.ID == 1 || p.ID == 3) {
+			t.Fatalf("unexpected entry for blog %d: %+v", p.ID, p)
+		}
+	}
+	if bytes.Contains(gunzip(t, buf.Bytes()), []byte("top secret")) {
+		t.Fatalf("file outside the content root was archived")
+	}
+	return root, staticDir, coll, buf.Bytes()
+}
+
+// gunzip 解压归档，用于检查原始内容
+func gunzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(gz)
+	return b
+}
+
+// rewriteArchive 逐条目改写归档；edit 返回 nil 表示保留原内容，extra 为追加的条目
+func rewriteArchive(t *testing.T, data []byte, edit func(name string, b []byte) []byte, extra map[string]string) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	write := func(name string, b []byte) {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(b))})
+		tw.Write(b)
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(tr)
+		if edit != nil {
+			if nb := edit(hdr.Name, b); nb != nil {
+				b = nb
+			}
+		}
+		write(hdr.Name, b)
+	}
+	for name, body := range extra {
+		write(name, []byte(body))
+	}
+	tw.Close()
+	gw.Close()
+	return out.Bytes()
+}
+
+// editManifest 修改清单中的博客条目
+func editManifest(t *testing.T, data []byte, edit func(m *archive.Manifest)) []byte {
+	return rewriteArchive(t, data, func(name string, b []byte) []byte {
+		if name != "manifest.json" {
+			return nil
+		}
+		var m archive.Manifest
+		if err := json.Unmarshal(b, &m); err != nil {
+			t.Fatal(err)
+		}
+		edit(&m)
+		nb, _ := json.Marshal(m)
+		return nb
+	}, nil)
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	root, staticDir, _, data := archiveFixture(t)
+	ctx := context.Background()
+
+	// 删除原文件后恢复到原路径
+	md := filepath.Join(root.Dir(), "posts", "hello.md")
+	os.RemoveAll(filepath.Join(root.Dir(), "posts"))
+	os.RemoveAll(filepath.Join(staticDir, "img"))
+
+	target := &fakeCollection{}
+	res, err := archive.Import(ctx, target, bytes.NewReader(data), archive.ImportOptions{StaticDir: staticDir, ContentRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Posts != 5 || res.FilesWritten != 3 || res.FilesSkipped != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if b, _ := os.ReadFile(md); string(b) != "# Hello\n" {
+		t.Fatalf("markdown not restored: %q", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(root.Dir(), "posts", "rel.md")); string(b) != "# Relative\n" {
+		t.Fatalf("relative-path markdown not restored: %q", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(staticDir, "img", "a.png")); string(b) != "png-bytes" {
+		t.Fatalf("static file not restored: %q", b)
+	}
+	if doc := target.replaced[1]; field(doc, "Title") != "Hello" || field(doc, "Path") != md {
+		t.Fatalf("blog not restored: %v", doc)
+	}
+
+	// 再次导入：内容一致，全部跳过
+	res, err = archive.Import(ctx, target, bytes.NewReader(data), archive.ImportOptions{StaticDir: staticDir, ContentRoot: root})
+	if err != nil || res.FilesWritten != 0 || res.FilesSkipped != 3 {
+		t.Fatalf("re-import should be a no-op: %+v %v", res, err)
+	}
+
+	// 指定 ContentDir：平铺写入，Path 改写为相对内容根目录的路径
+	dir := filepath.Join(root.Dir(), "restored")
+	target = &fakeCollection{}
+	if _, err := archive.Import(ctx, target, bytes.NewReader(data), archive.ImportOptions{ContentDir: dir, ContentRoot: root}); err != nil {
+		t.Fatal(err)
+	}
+	if got := field(target.replaced[1], "Path"); got != filepath.Join("restored", "hello.md") {
+		t.Fatalf("Path not rewritten: %v", got)
+	}
+	if c, err := root.Resolve(field(target.replaced[3], "Path").(string)); err != nil || filepath.Base(c) != "rel.md" {
+		t.Fatalf("rewritten Path does not resolve: %v %v", c, err)
+	}
+
+	// ContentDir 位于内容根目录之外：默认拒绝，显式允许时记为绝对路径
+	outside := t.TempDir()
+	if _, err := archive.Import(ctx, &fakeCollection{}, bytes.NewReader(data), archive.ImportOptions{ContentDir: outside, ContentRoot: root}); err == nil {
+		t.Fatalf("content dir outside the root accepted")
+	}
+	target = &fakeCollection{}
+	if _, err := archive.Import(ctx, target, bytes.NewReader(data), archive.ImportOptions{ContentDir: outside, AllowOutsideRoot: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := field(target.replaced[1], "Path"); got != filepath.Join(outside, "hello.md") {
+		t.Fatalf("Path outside root = %v", got)
+	}
+}
+
+func TestArchiveRejectsTampering(t *testing.T) {
+	root, _, _, data := archiveFixture(t)
+	ctx := context.Background()
+
+	cases := map[string][]byte{
+		"checksum": rewriteArchive(t, data, func(name string, b []byte) []byte {
+			if strings.HasPrefix(name, "content/") {
+				return []byte("# Tampered\n")
+			}
+			return nil
+		}, nil),
+		"extra entry": rewriteArchive(t, data, nil, map[string]string{"content/9/evil.md": "# Evil\n"}),
+		"content outside archive": editManifest(t, data, func(m *archive.Manifest) {
+			m.Posts[0].Content = "content/../manifest.json"
+		}),
+	}
+	for name, bad := range cases {
+		target := &fakeCollection{}
+		if _, err := archive.Import(ctx, target, bytes.NewReader(bad), archive.ImportOptions{ContentRoot: root}); err == nil {
+			t.Fatalf("%s: tampered archive accepted", name)
+		}
+		if len(target.replaced) != 0 {
+			t.Fatalf("%s: blogs written from a rejected archive", name)
+		}
+	}
+}
+
+func TestArchiveRejectsHostileOriginalPath(t *testing.T) {
+	root, _, _, data := archiveFixture(t)
+	ctx := context.Background()
+	outside := filepath.Join(t.TempDir(), "evil.md")
+
+	for _, p := range []string{outside, "../evil.md", "posts/../../evil.md"} {
+		bad := editManifest(t, data, func(m *archive.Manifest) { m.Posts[0].OriginalPath = p })
+		target := &fakeCollection{}
+		_, err := archive.Import(ctx, target, bytes.NewReader(bad), archive.ImportOptions{ContentRoot: root})
+		if err == nil || !strings.Contains(err.Error(), "refusing") {
+			t.Fatalf("%q: hostile path accepted: %v", p, err)
+		}
+		if len(target.replaced) != 0 {
+			t.Fatalf("%q: blogs written despite rejection", p)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(root.Dir()), "evil.md")); err == nil {
+			t.Fatalf("%q: file written outside content root", p)
+		}
+	}
+	if _, err := os.Stat(outside); err == nil {
+		t.Fatalf("file written to absolute path outside content root")
+	}
+
+	// 没有内容根目录时拒绝写回原路径；显式允许时才写到根目录之外，相对路径仍需内容根目录
+	bad := editManifest(t, data, func(m *archive.Manifest) { m.Posts[0].OriginalPath = outside })
+	if _, err := archive.Import(ctx, &fakeCollection{}, bytes.NewReader(bad), archive.ImportOptions{}); err == nil {
+		t.Fatalf("import without content root accepted")
+	}
+	if _, err := archive.Import(ctx, &fakeCollection{}, bytes.NewReader(bad), archive.ImportOptions{AllowOutsideRoot: true}); err == nil {
+		t.Fatalf("relative original path restored without a content root")
+	}
+	if _, err := archive.Import(ctx, &fakeCollection{}, bytes.NewReader(bad), archive.ImportOptions{AllowOutsideRoot: true, ContentRoot: root}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Fatalf("AllowOutsideRoot did not restore the file: %v", err)
+	}
+
+	// ContentDir 模式下 ".." 之类的文件名不会越出 ContentDir
+	dir := filepath.Join(root.Dir(), "restored")
+	bad = editManifest(t, data, func(m *archive.Manifest) { m.Posts[0].OriginalPath = ".." })
+	target := &fakeCollection{}
+	if _, err := archive.Import(ctx, target, bytes.NewReader(bad), archive.ImportOptions{ContentDir: dir, ContentRoot: root}); err != nil {
+		t.Fatal(err)
+	}
+	if got := field(target.replaced[1], "Path"); got != filepath.Join("restored", "1.md") {
+		t.Fatalf("unexpected Path for hostile name: %v", got)
+	}
+}
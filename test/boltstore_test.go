@@ -0,0 +1,219 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// openTempBolt 在临时目录中打开 bbolt 存储，测试结束时关闭
+func openTempBolt(t *testing.T, path string, root *utils.ContentRoot) *utils.BoltStore {
+	t.Helper()
+	b, err := utils.OpenBoltStore(path, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close(context.Background()) })
+	return b
+}
+
+func TestBoltStoreCRUD(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("hello"), 0o644)
+	root, err := utils.NewContentRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(t.TempDir(), "data", "blog.db")
+	b := openTempBolt(t, dbPath, root)
+	ctx := context.Background()
+
+	date := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	if err := b.SaveBlog(ctx, utils.BlogDocument{ID: 1, Title: "A", Summary: "first", Date: date, Path: "a.md"}); err != nil {
+		t.Fatal(err)
+	}
+	// 只覆盖非零字段
+	if err := b.SaveBlog(ctx, utils.BlogDocument{ID: 1, Title: "A2"}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := b.GetBlogPath(ctx, 1)
+	if err != nil || p.Title != "A2" || p.Path != "a.md" {
+		t.Fatalf("GetBlogPath = %+v, %v", p, err)
+	}
+	blogs, err := b.GetBlogInfo(ctx)
+	if err != nil || len(blogs) != 1 || blogs[0].Summary != "first" || !blogs[0].Date.Equal(date) {
+		t.Fatalf("GetBlogInfo = %+v, %v", blogs, err)
+	}
+	if c, err := b.GetBlogContentByID(ctx, 1); err != nil || c.Text != "hello" {
+		t.Fatalf("GetBlogContentByID = %+v, %v", c, err)
+	}
+
+	if err := b.DeleteBlog(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.GetBlogPath(ctx, 1); !errors.Is(err, utils.ErrBlogNotFound) {
+		t.Fatalf("expected ErrBlogNotFound after delete, got %v", err)
+	}
+	if c, _ := b.GetBlogContentByID(ctx, 1); c.ID != 404 {
+		t.Fatalf("deleted blog content = %+v", c)
+	}
+
+	if err := b.SaveBlog(ctx, utils.BlogDocument{ID: -1, Title: "neg"}); !errors.Is(err, utils.ErrNegativeBlogID) {
+		t.Fatalf("negative ID accepted: %v", err)
+	}
+
+	// 关闭后重新打开，数据仍在
+	b.SaveBlog(ctx, utils.BlogDocument{ID: 2, Title: "B"})
+	b.Close(ctx)
+	b = openTempBolt(t, dbPath, root)
+	if p, err := b.GetBlogPath(ctx, 2); err != nil || p.Title != "B" {
+		t.Fatalf("after reopen: %+v, %v", p, err)
+	}
+}
+
+func TestBoltStoreOrdering(t *testing.T) {
+	b := openTempBolt(t, filepath.Join(t.TempDir(), "blog.db"), nil)
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	// 跨越单字节边界的 ID 也应按数值升序
+	for _, doc := range []utils.BlogDocument{
+		{ID: 300, Title: "c", Date: day(3)},
+		{ID: 2, Title: "b", Date: day(5)},
+		{ID: 256, Title: "d", Date: day(5)},
+		{ID: 0, Title: "a", Date: day(1)},
+	} {
+		if err := b.SaveBlog(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blogs, err := b.GetBlogInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, bl := range blogs {
+		ids = append(ids, bl.ID)
+	}
+	if fmt.Sprint(ids) != "[0 2 256 300]" {
+		t.Fatalf("blogs not ordered by ID: %v", ids)
+	}
+
+	// 最新一篇按日期，同一日期取 ID 较大者
+	latest, err := b.GetLatestBlog(ctx)
+	if err != nil || latest.ID != 256 {
+		t.Fatalf("GetLatestBlog = %d, %v", latest.ID, err)
+	}
+
+	empty := openTempBolt(t, filepath.Join(t.TempDir(), "empty.db"), nil)
+	if _, err := empty.GetLatestBlog(ctx); err == nil {
+		t.Fatalf("latest blog of an empty store should fail")
+	}
+}
+
+// docSource 固定的博客列表，满足 utils.BlogDocumentSource
+type docSource []utils.BlogDocument
+
+func (s docSource) BlogDocuments(context.Context) ([]utils.BlogDocument, error) { return s, nil }
+
+func TestCopyToBolt(t *testing.T) {
+	ctx := context.Background()
+	updated := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	src := openTempBolt(t, filepath.Join(t.TempDir(), "src.db"), nil)
+	for _, doc := range []utils.BlogDocument{
+		{ID: 1, Title: "A", Summary: "s1", Slug: "a", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), DateTZ: "Asia/Shanghai", UpdatedAt: &updated, Path: "a.md"},
+		{ID: 2, Title: "B"},
+	} {
+		src.SaveBlog(ctx, doc)
+	}
+
+	dst := openTempBolt(t, filepath.Join(t.TempDir(), "dst.db"), nil)
+	dst.SaveBlog(ctx, utils.BlogDocument{ID: 99, Title: "replaced"})
+	n, err := utils.CopyToBolt(ctx, src, dst)
+	if err != nil || n != 2 {
+		t.Fatalf("CopyToBolt = %d, %v", n, err)
+	}
+	want, _ := src.BlogDocuments(ctx)
+	got, _ := dst.BlogDocuments(ctx)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("round trip mismatch:\nsrc %v\ndst %v", want, got)
+	}
+
+	// 重复或负数 ID 整体拒绝，目标保持不变
+	for name, bad := range map[string]docSource{
+		"duplicate": {{ID: 3}, {ID: 3}},
+		"negative":  {{ID: 4}, {ID: -1}},
+	} {
+		if _, err := utils.CopyToBolt(ctx, bad, dst); err == nil {
+			t.Fatalf("%s: copy accepted", name)
+		}
+		if got, _ := dst.BlogDocuments(ctx); len(got) != 2 {
+			t.Fatalf("%s: destination changed by a rejected copy: %v", name, got)
+		}
+	}
+}
+
+// TestMigrateToBolt 需要可用的 MongoDB，通过 MONGO_TEST_URI 指定，否则跳过
+func TestMigrateToBolt(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	ctx := context.Background()
+	m := utils.NewMongoManager(utils.MongoConfig{
+		URI:            uri,
+		Database:       "test",
+		Collection:     fmt.Sprintf("blogs_bolt_%d", time.Now().UnixNano()),
+		MaxPoolSize:    2,
+		ConnectTimeout: 5 * time.Second,
+		QueryTimeout:   5 * time.Second,
+		MinBackoff:     time.Second,
+		MaxBackoff:     time.Second,
+	}, nil)
+	defer m.Close(ctx)
+	coll, err := m.Blogs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coll.Drop(ctx)
+	updated := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	_, err = coll.InsertMany(ctx, []interface{}{
+		bson.D{{Key: "ID", Value: 1}, {Key: "Title", Value: "typed"}, {Key: "Summary", Value: "s1"},
+			{Key: "Date", Value: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}, {Key: "UpdatedAt", Value: updated}, {Key: "Path", Value: "a.md"}},
+		bson.D{{Key: "ID", Value: 2}, {Key: "Title", Value: "string"}, {Key: "Summary", Value: "s2"}, {Key: "Date", Value: "2023-12-31"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := openTempBolt(t, filepath.Join(t.TempDir(), "blog.db"), nil)
+	b.SaveBlog(ctx, utils.BlogDocument{ID: 99, Title: "replaced"})
+	n, err := utils.CopyToBolt(ctx, m, b)
+	if err != nil || n != 2 {
+		t.Fatalf("CopyToBolt = %d, %v", n, err)
+	}
+	want, _ := m.GetBlogInfo(ctx)
+	got, _ := b.GetBlogInfo(ctx)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("round trip mismatch:\nmongo %v\nbolt  %v", want, got)
+	}
+	if p, err := b.GetBlogPath(ctx, 1); err != nil || p.Path != "a.md" || !p.UpdatedAt.Equal(updated) {
+		t.Fatalf("path fields not copied: %+v, %v", p, err)
+	}
+
+	// 负数 ID 会破坏 bbolt 中的排序，整体拒绝且不改动目标文件
+	coll.InsertOne(ctx, bson.D{{Key: "ID", Value: -5}, {Key: "Title", Value: "neg"}})
+	if _, err := utils.CopyToBolt(ctx, m, b); !errors.Is(err, utils.ErrNegativeBlogID) {
+		t.Fatalf("negative ID copied: %v", err)
+	}
+	if got, _ := b.GetBlogInfo(ctx); len(got) != 2 {
+		t.Fatalf("bolt changed by a rejected copy: %v", got)
+	}
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressNegotiation(t *testing.T) {
+	body := strings.Repeat(`{"title":"hello"}`, 200)
+	h := handlers.Compress(handlers.CompressConfig{MinSize: 256, Types: []string{"application/json", "text/"}},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/small":
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{}`)
+			case "/image":
+				w.Header().Set("Content-Type", "image/png")
+				io.WriteString(w, body)
+			default:
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("ETag", `"abc"`)
+				if r.Header.Get("If-None-Match") == `"abc"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				io.WriteString(w, body)
+			}
+		}))
+
+	do := func(path, accept, inm string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		if inm != "" {
+			req.Header.Set("If-None-Match", inm)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	for accept, want := range map[string]string{
+		"gzip":                    "gzip",
+		"gzip, deflate, br, zstd": "br",
+		"gzip;q=1, br;q=0.5":      "gzip",
+		"zstd, br;q=0":            "zstd",
+		"*":                       "br",
+	} {
+		rec := do("/", accept, "")
+		if got := rec.Header().Get("Content-Encoding"); got != want {
+			t.Fatalf("Accept-Encoding %q: got %q, want %q", accept, got, want)
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" || rec.Header().Get("ETag") != `"abc-`+want+`"` {
+			t.Fatalf("unexpected headers: %v", rec.Header())
+		}
+		r, err := decoders[want](bytes.NewReader(rec.Body.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := io.ReadAll(r); string(got) != body {
+			t.Fatalf("%s: body mismatch (%d bytes)", want, len(got))
+		}
+	}
+
+	for _, tc := range []struct{ path, accept string }{
+		{"/", "identity"},
+		{"/small", "gzip"},
+		{"/image", "gzip"},
+	} {
+		if rec := do(tc.path, tc.accept, ""); rec.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("%s with %q should not be compressed", tc.path, tc.accept)
+		}
+	}
+
+	// 客户端携带压缩版本的 ETag 时仍能命中 304，并回送相同 ETag
+	rec := do("/", "gzip", `"abc-gzip"`)
+	if rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != `"abc-gzip"` {
+		t.Fatalf("revalidation: got %d etag=%q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
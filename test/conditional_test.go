@@ -0,0 +1,66 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+func TestConditionalBlogList(t *testing.T) {
+	store := newFakeStore()
+	date := time.Date(2024, 10, 31, 8, 0, 0, 0, time.UTC)
+	store.SaveBlog(context.Background(), utils.BlogDocument{ID: 1, Title: "A", Date: date})
+	store.SaveBlog(context.Background(), utils.BlogDocument{ID: 2, Title: "Old", Date: date.AddDate(0, -1, 0)})
+	srv := handlers.NewServer(store, handlers.CachePolicy{"/api/Blog": "public, max-age=60"})
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/Blog", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler(rec, req)
+		return rec
+	}
+
+	first := get("", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Body.Len() == 0 {
+		t.Fatalf("unexpected first response: %d etag=%q", first.Code, etag)
+	}
+	// 列表只依赖 ETag：删除博客不会让最大修改时间增大
+	if got := first.Header().Get("Last-Modified"); got != "" {
+		t.Fatalf("Last-Modified = %q", got)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Fatalf("Cache-Control = %q", got)
+	}
+
+	if rec := get("If-None-Match", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("If-None-Match: got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+	if rec := get("If-None-Match", `"other"`); rec.Code != http.StatusOK {
+		t.Fatalf("mismatched If-None-Match: got %d", rec.Code)
+	}
+	if rec := get("If-Modified-Since", date.Format(http.TimeFormat)); rec.Code != http.StatusOK {
+		t.Fatalf("If-Modified-Since without Last-Modified: got %d", rec.Code)
+	}
+
+	// 删除较旧的博客后列表变化，旧 ETag 失效
+	store.DeleteBlog(context.Background(), 2)
+	if rec := get("If-None-Match", etag); rec.Code != http.StatusOK {
+		t.Fatalf("list after delete: got %d", rec.Code)
+	}
+	etag = get("", "").Header().Get("ETag")
+
+	// 内容变化后旧 ETag 失效
+	store.SaveBlog(context.Background(), utils.BlogDocument{ID: 1, Title: "B"})
+	if rec := get("If-None-Match", etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("stale ETag still matched: %d", rec.Code)
+	}
+}
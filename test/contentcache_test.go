@@ -0,0 +1,239 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/api"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+func TestContentCache(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.md", "hello")
+	write("b.md", "world")
+	root, err := utils.NewContentRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newFakeStore()
+	store.SaveBlog(context.Background(), utils.BlogDocument{ID: 1, Path: "a.md"})
+	store.SaveBlog(context.Background(), utils.BlogDocument{ID: 2, Path: "b.md"})
+	cache := utils.NewContentCache(store, root, utils.ContentCacheConfig{MaxBytes: 1 << 20, MetaTTL: time.Minute})
+	ctx := context.Background()
+
+	get := func(id int) string {
+		c, err := cache.GetBlogContentByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetBlogContentByID(%d) error: %v", id, err)
+		}
+		return c.Text
+	}
+
+	if got := get(1); got != "hello" {
+		t.Fatalf("first read = %q", got)
+	}
+	if got := get(1); got != "hello" {
+		t.Fatalf("cached read = %q", got)
+	}
+	if s := cache.Stats(); s.Hits != 1 || s.Misses != 1 || s.Entries != 1 {
+		t.Fatalf("stats after hit = %+v", s)
+	}
+
+	// 修改文件（大小与 mtime 均变化）后应重新读取
+	write("a.md", "hello again")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "a.md"), future, future)
+	if got := get(1); got != "hello again" {
+		t.Fatalf("read after modify = %q", got)
+	}
+
+	// 不存在的博客返回 404 占位
+	if c, _ := cache.GetBlogContentByID(ctx, 99); c.ID != 404 {
+		t.Fatalf("missing blog = %+v", c)
+	}
+
+	// 容量只够一条时，读取第二篇会淘汰第一篇
+	small := utils.NewContentCache(store, root, utils.ContentCacheConfig{MaxBytes: 200, MetaTTL: time.Minute})
+	small.GetBlogContentByID(ctx, 1)
+	small.GetBlogContentByID(ctx, 2)
+	if s := small.Stats(); s.Entries != 1 || s.Evictions != 1 {
+		t.Fatalf("stats after eviction = %+v", s)
+	}
+}
+
+// gatedStore 元数据查询阻塞到 release 关闭，且像真实存储一样尊重 ctx
+type gatedStore struct {
+	*fakeStore
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (g *gatedStore) GetBlogPath(ctx context.Context, id int) (utils.BlogPath, error) {
+	g.calls.Add(1)
+	<-g.release
+	if err := ctx.Err(); err != nil {
+		return utils.BlogPath{}, err
+	}
+	return g.fakeStore.GetBlogPath(ctx, id)
+}
+
+func TestContentCacheConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := utils.NewContentRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &gatedStore{fakeStore: newFakeStore(), release: make(chan struct{})}
+	store.SaveBlog(context.Background(), utils.BlogDocument{ID: 1, Path: "a.md"})
+	// MetaTTL 极短：命中路径每次都会复核元数据
+	cache := utils.NewContentCache(store, root, utils.ContentCacheConfig{MaxBytes: 1 << 20, MetaTTL: time.Nanosecond})
+
+	// 第一个调用方发起加载后断开，不应让合并等待的其它调用方一起失败
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.GetBlogContentByID(ctx, 1)
+		first <- err
+	}()
+	for store.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := cache.GetBlogContentByID(context.Background(), 1)
+			if err == nil && c.Text != "hello" {
+				err = errors.New("unexpected body " + c.Text)
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-first:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("cancelled caller got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		close(store.release)
+		t.Fatal("cancelled caller still waiting for the shared load")
+	}
+	close(store.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("waiter failed: %v", err)
+		}
+	}
+	if n := store.calls.Load(); n != 1 {
+		t.Fatalf("concurrent misses should share one load, got %d", n)
+	}
+
+	// 已缓存后并发命中并复核元数据（配合 -race 检查 checkedAt 的读写）
+	wg = sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if c, err := cache.GetBlogContentByID(context.Background(), 1); err != nil || c.Text != "hello" {
+					t.Errorf("concurrent hit = %q, %v", c.Text, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// metaCountingStore 统计列表与最新一篇的存储查询次数
+type metaCountingStore struct {
+	*fakeStore
+	list, latest atomic.Int32
+}
+
+func (m *metaCountingStore) GetBlogInfo(ctx context.Context) ([]api.BlogResponse, error) {
+	m.list.Add(1)
+	return m.fakeStore.GetBlogInfo(ctx)
+}
+
+func (m *metaCountingStore) GetLatestBlog(ctx context.Context) (api.BlogResponse, error) {
+	m.latest.Add(1)
+	return m.fakeStore.GetLatestBlog(ctx)
+}
+
+func TestContentCacheMetadata(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A\n\nbody"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := utils.NewContentRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	store := &metaCountingStore{fakeStore: newFakeStore()}
+	store.SaveBlog(ctx, utils.BlogDocument{ID: 1, Title: "A", Path: "a.md", Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	cache := utils.NewContentCache(store, root, utils.ContentCacheConfig{MaxBytes: 1 << 20, MetaTTL: time.Minute})
+
+	// MetaTTL 内列表与最新一篇只查询一次存储
+	for i := 0; i < 3; i++ {
+		list, err := cache.GetBlogInfo(ctx)
+		if err != nil || len(list) != 1 || list[0].Title != "A" {
+			t.Fatalf("GetBlogInfo = %+v, %v", list, err)
+		}
+		list[0].Title = "mutated by caller"
+		if latest, err := cache.GetLatestBlog(ctx); err != nil || latest.ID != 1 {
+			t.Fatalf("GetLatestBlog = %+v, %v", latest, err)
+		}
+	}
+	if store.list.Load() != 1 || store.latest.Load() != 1 {
+		t.Fatalf("store queried %d/%d times, want 1/1", store.list.Load(), store.latest.Load())
+	}
+	if s := cache.Stats(); s.Hits != 4 || s.Misses != 2 || s.Bytes == 0 {
+		t.Fatalf("stats = %+v", s)
+	}
+
+	// 内容监视器写入新博客后立即失效，不必等 MetaTTL
+	w := utils.NewContentWatcher(root, store, store, cache, time.Hour)
+	w.Poll(ctx) // 基线
+	if err := os.WriteFile(filepath.Join(dir, "b.md"), []byte("---\ndate: 2025-01-01\n---\n# B\n\nnewer"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w.Poll(ctx)
+	if list, _ := cache.GetBlogInfo(ctx); len(list) != 2 {
+		t.Fatalf("list not refreshed after watcher update: %+v", list)
+	}
+	if latest, _ := cache.GetLatestBlog(ctx); latest.Title != "B" {
+		t.Fatalf("latest not refreshed after watcher update: %+v", latest)
+	}
+
+	// 关闭缓存时直接委托给存储
+	off := utils.NewContentCache(store, root, utils.ContentCacheConfig{MetaTTL: time.Minute})
+	before := store.list.Load()
+	off.GetBlogInfo(ctx)
+	off.GetBlogInfo(ctx)
+	if store.list.Load()-before != 2 {
+		t.Fatalf("disabled cache still cached the list")
+	}
+}
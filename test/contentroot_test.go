@@ -0,0 +1,49 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+func TestContentRootResolve(t *testing.T) {
+	base := t.TempDir()
+	rootDir := filepath.Join(base, "content")
+	if err := os.MkdirAll(filepath.Join(rootDir, "posts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	inside := filepath.Join(rootDir, "posts", "a.md")
+	outside := filepath.Join(base, "secret.txt")
+	for _, p := range []string{inside, outside} {
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(rootDir, "posts", "link.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := utils.NewContentRoot(rootDir)
+	if err != nil {
+		t.Fatalf("NewContentRoot error: %v", err)
+	}
+
+	for _, p := range []string{inside, "posts/a.md"} {
+		if _, err := root.Resolve(p); err != nil {
+			t.Fatalf("Resolve(%q) error: %v", p, err)
+		}
+	}
+	// 越界路径即使文件不存在也必须报告越界，而不是 ErrNotExist
+	missingOutside := filepath.Join(base, "nope", "id_rsa")
+	for _, p := range []string{outside, "../secret.txt", "posts/link.md", "../../etc/nope", "posts/../../nope.md", missingOutside} {
+		if _, err := root.Resolve(p); !errors.Is(err, utils.ErrPathEscapesRoot) {
+			t.Fatalf("Resolve(%q) = %v, want ErrPathEscapesRoot", p, err)
+		}
+	}
+	if _, err := root.Resolve("posts/missing.md"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Resolve(missing) = %v, want ErrNotExist", err)
+	}
+}
@@ -0,0 +1,32 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+func TestParseBlogDate(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	want := time.Date(2024, 10, 31, 0, 0, 0, 0, loc)
+	cases := []string{
+		"2024-10-31",
+		"2024/10/31",
+		"2024年10月31日",
+		"2024-10-31T00:00:00+08:00",
+		" 2024-10-31 00:00:00 ",
+	}
+	for _, s := range cases {
+		got, err := utils.ParseBlogDate(s, loc)
+		if err != nil {
+			t.Fatalf("ParseBlogDate(%q) error: %v", s, err)
+		}
+		if !got.Equal(want) {
+			t.Fatalf("ParseBlogDate(%q) = %s, want %s", s, got, want)
+		}
+	}
+	if _, err := utils.ParseBlogDate("yesterday", loc); err == nil {
+		t.Fatalf("expected error for unrecognized date")
+	}
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/LtePrince/Personal-Website-backend/api"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// fakeStore 内存中的 BlogStore / BlogWriter，用于不依赖数据库的测试
+type fakeStore struct {
+	mu   sync.Mutex
+	docs map[int]utils.BlogDocument
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{docs: make(map[int]utils.BlogDocument)}
+}
+
+func (f *fakeStore) GetBlogInfo(context.Context) ([]api.BlogResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []api.BlogResponse
+	for _, d := range f.docs {
+		out = append(out, api.BlogResponse{ID: d.ID, Title: d.Title, Summary: d.Summary, Date: d.Date, UpdatedAt: d.UpdatedAt})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (f *fakeStore) GetLatestBlog(ctx context.Context) (api.BlogResponse, error) {
+	all, _ := f.GetBlogInfo(ctx)
+	var latest api.BlogResponse
+	for _, b := range all {
+		if b.Date.After(latest.Date) {
+			latest = b
+		}
+	}
+	return latest, nil
+}
+
+func (f *fakeStore) GetBlogContentByID(context.Context, int) (api.BlogContent, error) {
+	return api.BlogContent{}, nil
+}
+
+func (f *fakeStore) GetBlogPath(_ context.Context, id int) (utils.BlogPath, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d, ok := f.docs[id]
+	if !ok {
+		return utils.BlogPath{}, utils.ErrBlogNotFound
+	}
+	return utils.BlogPath{ID: d.ID, Title: d.Title, Path: d.Path, UpdatedAt: d.UpdatedAt}, nil
+}
+
+func (f *fakeStore) ListBlogPaths(ctx context.Context) ([]utils.BlogPath, error) {
+	f.mu.Lock()
+	ids := make([]int, 0, len(f.docs))
+	for id := range f.docs {
+		ids = append(ids, id)
+	}
+	f.mu.Unlock()
+	sort.Ints(ids)
+	out := make([]utils.BlogPath, 0, len(ids))
+	for _, id := range ids {
+		p, _ := f.GetBlogPath(ctx, id)
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (f *fakeStore) Close(context.Context) error { return nil }
+
+// SaveBlog 与真实存储一致：只覆盖非零字段
+func (f *fakeStore) SaveBlog(_ context.Context, in utils.BlogDocument) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := f.docs[in.ID]
+	d.ID = in.ID
+	if in.Title != "" {
+		d.Title = in.Title
+	}
+	if in.Summary != "" {
+		d.Summary = in.Summary
+	}
+	if !in.Date.IsZero() {
+		d.Date = in.Date
+	}
+	if in.UpdatedAt != nil {
+		d.UpdatedAt = in.UpdatedAt
+	}
+	if in.Path != "" {
+		d.Path = in.Path
+	}
+	f.docs[in.ID] = d
+	return nil
+}
+
+func (f *fakeStore) DeleteBlog(_ context.Context, id int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.docs, id)
+	return nil
+}
+
+func (f *fakeStore) get(id int) (utils.BlogDocument, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d, ok := f.docs[id]
+	return d, ok
+}
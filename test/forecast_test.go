@@ -0,0 +1,179 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// forecastPayload 构造 Open-Meteo 预报响应：逐小时从 start 起 hours 个点，逐日从 dayStart 起 days 天
+func forecastPayload(t *testing.T, start time.Time, hours int, dayStart time.Time, days int) string {
+	t.Helper()
+	const layout = "2006-01-02T15:04"
+	var hourly struct {
+		Time []string   `json:"time"`
+		Temp []*float64 `json:"temperature_2m"`
+		Prob []*float64 `json:"precipitation_probability"`
+		Code []*int     `json:"weather_code"`
+	}
+	for i := 0; i < hours; i++ {
+		temp, prob, code := float64(i)+0.6, float64(i%100), 61
+		hourly.Time = append(hourly.Time, start.Add(time.Duration(i)*time.Hour).Format(layout))
+		hourly.Temp = append(hourly.Temp, &temp)
+		hourly.Prob = append(hourly.Prob, &prob)
+		hourly.Code = append(hourly.Code, &code)
+	}
+	// 最后一个点缺失温度
+	hourly.Temp[hours-1] = nil
+
+	daily := map[string][]any{}
+	for i := 0; i < days; i++ {
+		day := dayStart.AddDate(0, 0, i)
+		daily["time"] = append(daily["time"], day.Format("2006-01-02"))
+		daily["weather_code"] = append(daily["weather_code"], 0)
+		daily["temperature_2m_max"] = append(daily["temperature_2m_max"], 25.4)
+		daily["temperature_2m_min"] = append(daily["temperature_2m_min"], 14.5)
+		daily["precipitation_probability_max"] = append(daily["precipitation_probability_max"], 30)
+		daily["sunrise"] = append(daily["sunrise"], day.Format("2006-01-02")+"T06:12")
+		daily["sunset"] = append(daily["sunset"], day.Format("2006-01-02")+"T18:03")
+	}
+
+	b, err := json.Marshal(map[string]any{
+		"timezone":           "Asia/Shanghai",
+		"utc_offset_seconds": 8 * 3600,
+		"hourly":             hourly,
+		"daily":              daily,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFetchForecast(t *testing.T) {
+	shanghai := time.FixedZone("Asia/Shanghai", 8*3600)
+	now := time.Now().In(shanghai)
+	// 数据从两小时前、前一天开始，模拟缓存中已变旧的预报
+	start := now.Truncate(time.Hour).Add(-2 * time.Hour)
+	yesterday := now.AddDate(0, 0, -1)
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusOK, forecastPayload(t, start, 48, yesterday, 8)))
+
+	fc, err := utils.FetchForecast(context.Background(), 31.2, 121.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Timezone != "Asia/Shanghai" || len(fc.Hourly) != 48 || len(fc.Daily) != 8 {
+		t.Fatalf("unexpected forecast: tz=%s hourly=%d daily=%d", fc.Timezone, len(fc.Hourly), len(fc.Daily))
+	}
+	if _, off := fc.Hourly[0].Time.Zone(); off != 8*3600 || !fc.Hourly[0].Time.Equal(start) {
+		t.Fatalf("hourly time not parsed in forecast timezone: %s", fc.Hourly[0].Time)
+	}
+	if *fc.Hourly[0].WeatherCode != 61 || fc.Hourly[47].TempC != nil {
+		t.Fatalf("unexpected hourly point: %+v / %+v", fc.Hourly[0], fc.Hourly[47])
+	}
+
+	hours := fc.NextHours(now, utils.ForecastHours)
+	if len(hours) != utils.ForecastHours || !hours[0].Time.Equal(now.Truncate(time.Hour)) {
+		t.Fatalf("NextHours should start at the current hour: %d points, first %s", len(hours), hours[0].Time)
+	}
+	days := fc.DaysFrom(now, utils.ForecastDays)
+	if len(days) != utils.ForecastDays || days[0].Date != now.Format("2006-01-02") {
+		t.Fatalf("DaysFrom should start today: %d days, first %s", len(days), days[0].Date)
+	}
+	if days[0].Sunrise == nil || days[0].Sunrise.Hour() != 6 || *days[0].TempMaxC != 25.4 || *days[0].WeatherCode != 0 {
+		t.Fatalf("unexpected daily point: %+v", days[0])
+	}
+}
+
+func TestFetchForecastRequestsExtraDay(t *testing.T) {
+	// 多拉一天：缓存跨过当地午夜后仍能返回完整的 7 天
+	days := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		days <- r.URL.Query().Get("forecast_days")
+		now := time.Now()
+		w.Write([]byte(forecastPayload(t, now, 48, now, 8)))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OPEN_METEO_FORECAST_URL", srv.URL)
+
+	if _, err := utils.FetchForecast(context.Background(), 31.2, 121.5); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-days; got != "8" {
+		t.Fatalf("forecast_days = %s, want 8", got)
+	}
+}
+
+func TestFetchForecastDST(t *testing.T) {
+	// 纽约 2026-03-08 02:00 进入夏令时，当地时间跳过 02:00；响应里的偏移只是当前的 -5 小时
+	body := `{"timezone":"America/New_York","utc_offset_seconds":-18000,` +
+		`"hourly":{"time":["2026-03-08T00:00","2026-03-08T01:00","2026-03-08T03:00"]},` +
+		`"daily":{"time":["2026-03-08","2026-03-09"],"sunrise":["2026-03-08T07:23","2026-03-09T07:22"]}}`
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusOK, body))
+
+	fc, err := utils.FetchForecast(context.Background(), 40.7, -74)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, off := fc.Hourly[0].Time.Zone(); off != -5*3600 {
+		t.Fatalf("offset before DST = %d", off)
+	}
+	if _, off := fc.Hourly[2].Time.Zone(); off != -4*3600 {
+		t.Fatalf("offset after DST = %d, want -4h", off)
+	}
+	if gap := fc.Hourly[2].Time.Sub(fc.Hourly[1].Time); gap != time.Hour {
+		t.Fatalf("01:00 -> 03:00 across DST should be one hour, got %s", gap)
+	}
+	if _, off := fc.Daily[1].Sunrise.Zone(); off != -4*3600 {
+		t.Fatalf("sunrise offset after DST = %d", off)
+	}
+
+	// 无法识别的时区名：退回固定偏移
+	body = `{"timezone":"Mars/Olympus","utc_offset_seconds":3600,"hourly":{"time":["2026-03-08T00:00"]}}`
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusOK, body))
+	fc, err = utils.FetchForecast(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, off := fc.Hourly[0].Time.Zone(); off != 3600 {
+		t.Fatalf("fallback offset = %d, want 3600", off)
+	}
+}
+
+func TestFetchForecastError(t *testing.T) {
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusServiceUnavailable, "down"))
+	_, err := utils.FetchForecast(context.Background(), 1, 2)
+	var se *utils.SourceError
+	if !errors.As(err, &se) || se.Source != utils.SourceForecast {
+		t.Fatalf("expected SourceError for %s, got %v", utils.SourceForecast, err)
+	}
+}
+
+func TestWeatherCacheForecast(t *testing.T) {
+	now := time.Now()
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusOK, forecastPayload(t, now, 48, now, 7)))
+	cache := utils.NewWeatherCache(utils.WeatherCacheConfig{Grid: 0.1, TTL: time.Minute, MaxStale: time.Hour, MaxEntries: 10}, nil)
+	ctx := context.Background()
+
+	first, err := cache.Forecast(ctx, 31.21, 121.47)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同一网格命中缓存
+	second, err := cache.Forecast(ctx, 31.24, 121.52)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.FetchedAt.Equal(second.FetchedAt) {
+		t.Fatalf("second lookup in the same grid cell should hit the cache")
+	}
+	if st := cache.Stats(); st.Hits != 1 || st.Misses != 1 || st.Entries != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+func TestOfflineGeocoder(t *testing.T) {
+	g := utils.NewOfflineGeocoder()
+	ctx := context.Background()
+	for _, name := range []string{"上海", "上海市", "shanghai", "  SHANGHAI "} {
+		p, err := g.Geocode(ctx, name, utils.LangZH)
+		if err != nil || p.Name != "上海" || p.CountryCode != "CN" || p.Source != utils.SourceOffline {
+			t.Fatalf("Geocode(%q) = %+v, %v", name, p, err)
+		}
+	}
+	if p, err := g.Geocode(ctx, "上海", utils.LangEN); err != nil || p.Name != "Shanghai" {
+		t.Fatalf("Geocode(上海, en) = %+v, %v", p, err)
+	}
+	if p, err := g.Geocode(ctx, "new   york", utils.LangZH); err != nil || p.Latitude < 40 || p.Longitude > -73 {
+		t.Fatalf("Geocode(new york) = %+v, %v", p, err)
+	}
+	if _, err := g.Geocode(ctx, "Atlantis", utils.LangZH); !errors.Is(err, utils.ErrPlaceNotFound) {
+		t.Fatalf("expected ErrPlaceNotFound, got %v", err)
+	}
+}
+
+func TestGeocoderChain(t *testing.T) {
+	ctx := context.Background()
+	chain := utils.GeocoderChain{utils.OpenMeteoGeocoder{}, utils.NewOfflineGeocoder()}
+
+	t.Setenv("OPEN_METEO_GEOCODING_URL", openMeteoStandIn(t, 0, http.StatusOK,
+		`{"results":[{"name":"Hangzhou","admin1":"Zhejiang","country_code":"CN","latitude":30.29,"longitude":120.16}]}`))
+	p, err := chain.Geocode(ctx, "杭州", utils.LangZH)
+	if err != nil || p.Source != utils.SourceGeocoding || p.Region != "Zhejiang" {
+		t.Fatalf("online result expected, got %+v, %v", p, err)
+	}
+
+	// 地名语言随 lang 传给上游
+	var language string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		language = r.URL.Query().Get("language")
+		w.Write([]byte(`{"results":[{"name":"Hangzhou","country_code":"CN","latitude":30.29,"longitude":120.16}]}`))
+	}))
+	defer srv.Close()
+	t.Setenv("OPEN_METEO_GEOCODING_URL", srv.URL)
+	if _, err := chain.Geocode(ctx, "杭州", utils.LangEN); err != nil || language != utils.LangEN {
+		t.Fatalf("language = %q, %v", language, err)
+	}
+
+	// 在线无结果：回退到内置列表
+	t.Setenv("OPEN_METEO_GEOCODING_URL", openMeteoStandIn(t, 0, http.StatusOK, `{}`))
+	if p, err := chain.Geocode(ctx, "杭州", utils.LangZH); err != nil || p.Source != utils.SourceOffline {
+		t.Fatalf("offline fallback expected, got %+v, %v", p, err)
+	}
+	if _, err := chain.Geocode(ctx, "Atlantis", utils.LangZH); !errors.Is(err, utils.ErrPlaceNotFound) {
+		t.Fatalf("expected ErrPlaceNotFound, got %v", err)
+	}
+
+	// 在线故障：内置列表能找到则仍成功，找不到时返回故障而非“不存在”
+	t.Setenv("OPEN_METEO_GEOCODING_URL", openMeteoStandIn(t, 0, http.StatusBadGateway, "down"))
+	if p, err := chain.Geocode(ctx, "Tokyo", utils.LangZH); err != nil || p.Source != utils.SourceOffline {
+		t.Fatalf("offline fallback expected, got %+v, %v", p, err)
+	}
+	if _, err := chain.Geocode(ctx, "Atlantis", utils.LangZH); err == nil || errors.Is(err, utils.ErrPlaceNotFound) {
+		t.Fatalf("expected upstream error, got %v", err)
+	}
+}
+
+func TestWeatherLocationParams(t *testing.T) {
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusOK, forecastBody))
+	t.Setenv("OPEN_METEO_AIR_QUALITY_URL", openMeteoStandIn(t, 0, http.StatusOK, `{"current":{"us_aqi":42}}`))
+	t.Setenv("OPEN_METEO_GEOCODING_URL", openMeteoStandIn(t, 0, http.StatusOK, `{"results":[]}`))
+
+	get := func(query string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodGet, "/api/Weather?"+query, nil)
+		rec := httptest.NewRecorder()
+		handlers.WeatherHandler(rec, req)
+		var body map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec, body
+	}
+
+	rec, body := get("lat=-33.87&lon=151.21")
+	if rec.Code != http.StatusOK || body["locationSource"] != handlers.SourceQuery || body["temperatureC"] != float64(22) {
+		t.Fatalf("lat/lon: %d %v", rec.Code, body)
+	}
+	if body["location"] != "-33.8700, 151.2100" {
+		t.Fatalf("coordinate-only location = %v", body["location"])
+	}
+
+	rec, body = get("city=Sydney")
+	if rec.Code != http.StatusOK || body["locationSource"] != utils.SourceOffline || body["city"] != "悉尼" {
+		t.Fatalf("city: %d %v", rec.Code, body)
+	}
+	if _, body := get("city=Sydney&lang=en"); body["city"] != "Sydney" {
+		t.Fatalf("city with lang=en: %v", body)
+	}
+
+	for _, q := range []string{"lat=91&lon=0", "lat=10", "lat=abc&lon=1"} {
+		if rec, _ := get(q); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d, want 400", q, rec.Code)
+		}
+	}
+	if rec, _ := get("city=Atlantis"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown city: status %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,173 @@
+package test
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+	_ "golang.org/x/image/webp"
+)
+
+func TestImageResizer(t *testing.T) {
+	staticDir, cacheDir := t.TempDir(), t.TempDir()
+	var src bytes.Buffer
+	jpeg.Encode(&src, image.NewRGBA(image.Rect(0, 0, 800, 400)), nil)
+	os.WriteFile(filepath.Join(staticDir, "photo.jpg"), src.Bytes(), 0o644)
+	src.Reset()
+	png.Encode(&src, image.NewRGBA(image.Rect(0, 0, 800, 400)))
+	os.WriteFile(filepath.Join(staticDir, "chart.png"), src.Bytes(), 0o644)
+	os.WriteFile(filepath.Join(staticDir, "notes.txt"), []byte("hello"), 0o644)
+
+	z := handlers.NewImageResizer(handlers.ImageResizeConfig{
+		StaticDir:      staticDir,
+		CacheDir:       cacheDir,
+		Widths:         []int{320, 640},
+		Qualities:      []int{75},
+		DefaultQuality: 75,
+		MaxPixels:      1 << 22,
+		CacheControl:   "public, max-age=60",
+	})
+	h := http.StripPrefix("/static/", z.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})))
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := get("/static/chart.png?w=320&fmt=webp")
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/webp" {
+			t.Fatalf("resize: got %d %v", rec.Code, rec.Header())
+		}
+		cfg, format, err := image.DecodeConfig(rec.Body)
+		if err != nil || format != "webp" || cfg.Width != 320 || cfg.Height != 160 {
+			t.Fatalf("unexpected output: %s %dx%d %v", format, cfg.Width, cfg.Height, err)
+		}
+	}
+	cached, _ := filepath.Glob(filepath.Join(cacheDir, "*", "*", "*.webp"))
+	if len(cached) != 1 {
+		t.Fatalf("expected one cached file, got %v", cached)
+	}
+
+	// 默认沿用源格式（JPEG）
+	rec := get("/static/photo.jpg?w=640&q=75")
+	if cfg, _, _ := image.DecodeConfig(rec.Body); rec.Code != http.StatusOK || cfg.Width != 640 {
+		t.Fatalf("jpeg resize: got %d width=%d", rec.Code, cfg.Width)
+	}
+
+	for url, want := range map[string]int{
+		"/static/photo.jpg?w=333":    http.StatusBadRequest,
+		"/static/photo.jpg?q=10":     http.StatusBadRequest,
+		"/static/photo.jpg?fmt=tiff": http.StatusBadRequest,
+		"/static/photo.jpg?fmt=webp": http.StatusBadRequest, // WebP 只能无损编码
+		"/static/chart.png?q=75":     http.StatusBadRequest, // q 对无损输出无效
+		"/static/notes.txt?w=320":    http.StatusUnsupportedMediaType,
+		"/static/missing.jpg?w=320":  http.StatusNotFound,
+		"/static/photo.jpg":          http.StatusTeapot, // 无参数时交给下游静态处理
+	} {
+		if rec := get(url); rec.Code != want {
+			t.Fatalf("%s: got %d, want %d", url, rec.Code, want)
+		}
+	}
+}
+
+func TestImageResizerCacheCleanup(t *testing.T) {
+	staticDir, cacheDir := t.TempDir(), t.TempDir()
+	writeJPEG := func(name string, width int) {
+		var b bytes.Buffer
+		jpeg.Encode(&b, image.NewRGBA(image.Rect(0, 0, width, 400)), nil)
+		os.WriteFile(filepath.Join(staticDir, name), b.Bytes(), 0o644)
+	}
+	writeJPEG("a.jpg", 800)
+	writeJPEG("b.jpg", 800)
+
+	cfg := handlers.ImageResizeConfig{
+		StaticDir:      staticDir,
+		CacheDir:       cacheDir,
+		Widths:         []int{320, 640},
+		Qualities:      []int{75},
+		DefaultQuality: 75,
+		MaxPixels:      1 << 22,
+	}
+	variants := func() []string {
+		files, _ := filepath.Glob(filepath.Join(cacheDir, "*", "*", "*.jpg"))
+		return files
+	}
+	get := func(z *handlers.ImageResizer, url string) {
+		rec := httptest.NewRecorder()
+		z.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d", url, rec.Code)
+		}
+	}
+
+	// 源图更新后，旧版本的变体随新版本生成而删除
+	z := handlers.NewImageResizer(cfg)
+	get(z, "/a.jpg?w=320")
+	get(z, "/a.jpg?w=640")
+	if n := len(variants()); n != 2 {
+		t.Fatalf("expected 2 variants, got %d", n)
+	}
+	writeJPEG("a.jpg", 900)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(staticDir, "a.jpg"), future, future)
+	get(z, "/a.jpg?w=320")
+	if n := len(variants()); n != 1 {
+		t.Fatalf("stale variants not removed: %v", variants())
+	}
+
+	// 超出容量上限时在后台淘汰最久未使用的变体，宽限期内的（包括刚生成的）保留
+	stale := variants()[0]
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(stale, old, old)
+	cfg.CacheMaxBytes = 1
+	cfg.EvictGrace = time.Hour
+	cfg.SweepInterval = time.Hour
+	z = handlers.NewImageResizer(cfg)
+	get(z, "/b.jpg?w=320")
+	waitFor(t, func() bool {
+		_, err := os.Stat(stale)
+		return os.IsNotExist(err)
+	})
+	if len(variants()) != 1 {
+		t.Fatalf("expected only the newest variant to survive, got %v", variants())
+	}
+	get(z, "/b.jpg?w=320")
+	if len(variants()) != 1 {
+		t.Fatalf("cache hit should not evict the served variant")
+	}
+
+	// 距上次淘汰不足 SweepInterval：再次未命中不触发淘汰
+	kept := variants()[0]
+	os.Chtimes(kept, old, old)
+	get(z, "/b.jpg?w=640")
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(kept); err != nil {
+		t.Fatalf("sweep ran again within SweepInterval: %v", err)
+	}
+	if len(variants()) != 2 {
+		t.Fatalf("expected 2 variants, got %v", variants())
+	}
+}
+
+// waitFor 轮询直到 cond 成立，用于等待后台任务
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
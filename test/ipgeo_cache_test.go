@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// countingLocator 记录调用次数；fail 中的 IP 返回错误
+type countingLocator struct {
+	calls atomic.Int32
+	delay time.Duration
+	fail  map[string]bool
+}
+
+func (l *countingLocator) Lookup(_ context.Context, ip string) (*utils.IPInfo, error) {
+	l.calls.Add(1)
+	time.Sleep(l.delay)
+	if l.fail[ip] {
+		return nil, errors.New("not found")
+	}
+	return &utils.IPInfo{IP: ip, City: "City-" + ip, Source: "test"}, nil
+}
+
+func TestIPGeoCache(t *testing.T) {
+	ctx := context.Background()
+	loc := &countingLocator{delay: 20 * time.Millisecond, fail: map[string]bool{"198.51.100.9": true}}
+	cache := utils.NewIPGeoCache(loc, utils.IPGeoCacheConfig{MaxEntries: 2, TTL: time.Hour, NegativeTTL: 50 * time.Millisecond})
+
+	// 并发查询同一 IP 只请求一次
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if info, err := cache.Lookup(ctx, "203.0.113.1"); err != nil || info.City != "City-203.0.113.1" {
+				t.Errorf("lookup: %+v %v", info, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := loc.calls.Load(); n != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", n)
+	}
+	cache.Lookup(ctx, "203.0.113.1")
+	if n := loc.calls.Load(); n != 1 {
+		t.Fatalf("cached lookup hit upstream (%d calls)", n)
+	}
+
+	// 失败结果短期缓存，过期后重新查询
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Lookup(ctx, "198.51.100.9"); err == nil {
+			t.Fatalf("expected cached failure")
+		}
+	}
+	if n := loc.calls.Load(); n != 2 {
+		t.Fatalf("negative result not cached (%d calls)", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	cache.Lookup(ctx, "198.51.100.9")
+	if n := loc.calls.Load(); n != 3 {
+		t.Fatalf("negative entry did not expire (%d calls)", n)
+	}
+
+	// 超出容量时淘汰最久未用的条目
+	cache.Lookup(ctx, "203.0.113.2")
+	cache.Lookup(ctx, "203.0.113.3")
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Evictions == 0 || stats.Hits == 0 || stats.Misses == 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
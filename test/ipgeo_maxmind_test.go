@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// ---- 最小 MaxMind DB 编码，仅用于生成测试库 ----
+
+func mmdbCtrl(typ, size int) []byte {
+	if typ <= 7 {
+		return []byte{byte(typ<<5 | size)}
+	}
+	return []byte{byte(size), byte(typ - 7)} // 扩展类型
+}
+
+func mmdbString(s string) []byte { return append(mmdbCtrl(2, len(s)), s...) }
+
+func mmdbDouble(f float64) []byte {
+	return binary.BigEndian.AppendUint64(mmdbCtrl(3, 8), math.Float64bits(f))
+}
+
+func mmdbUint(typ int, v uint64) []byte {
+	var b []byte
+	for ; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	return append(mmdbCtrl(typ, len(b)), b...)
+}
+
+func mmdbMap(m map[string][]byte) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := mmdbCtrl(7, len(m))
+	for _, k := range keys {
+		out = append(append(out, mmdbString(k)...), m[k]...)
+	}
+	return out
+}
+
+func mmdbNames(en string) []byte {
+	return mmdbMap(map[string][]byte{"names": mmdbMap(map[string][]byte{"en": mmdbString(en)})})
+}
+
+// writeMMDB 生成只包含 network/24 一条记录的 IPv4 GeoLite2-City 格式数据库
+func writeMMDB(t *testing.T, path string, network net.IP, city string) {
+	record := mmdbMap(map[string][]byte{
+		"city":         mmdbNames(city),
+		"country":      mmdbMap(map[string][]byte{"iso_code": mmdbString("ZZ"), "names": mmdbMap(map[string][]byte{"en": mmdbString("Testland")})}),
+		"subdivisions": append(mmdbCtrl(11, 1), mmdbNames("Test Region")...),
+		"location":     mmdbMap(map[string][]byte{"latitude": mmdbDouble(12.5), "longitude": mmdbDouble(-45.25)}),
+	})
+
+	// 搜索树：沿 /24 前缀逐位下行，最后一位指向数据区；其余分支为“未找到”(= nodeCount)
+	const nodeCount, prefix = 24, 24
+	ip := binary.BigEndian.Uint32(network.To4())
+	var tree []byte
+	put24 := func(v uint32) { tree = append(tree, byte(v>>16), byte(v>>8), byte(v)) }
+	for i := 0; i < prefix; i++ {
+		next := uint32(i + 1)
+		if i == prefix-1 {
+			next = nodeCount + 16 // 数据区偏移 0
+		}
+		if ip>>(31-i)&1 == 0 {
+			put24(next)
+			put24(nodeCount)
+		} else {
+			put24(nodeCount)
+			put24(next)
+		}
+	}
+
+	meta := mmdbMap(map[string][]byte{
+		"node_count":                  mmdbUint(6, nodeCount),
+		"record_size":                 mmdbUint(5, 24),
+		"ip_version":                  mmdbUint(5, 4),
+		"database_type":               mmdbString("GeoLite2-City"),
+		"languages":                   append(mmdbCtrl(11, 1), mmdbString("en")...),
+		"binary_format_major_version": mmdbUint(5, 2),
+		"binary_format_minor_version": mmdbUint(5, 0),
+		"build_epoch":                 mmdbUint(9, uint64(time.Now().Unix())),
+		"description":                 mmdbMap(map[string][]byte{"en": mmdbString("test")}),
+	})
+
+	var db []byte
+	db = append(db, tree...)
+	db = append(db, make([]byte, 16)...)
+	db = append(db, record...)
+	db = append(db, "\xab\xcd\xefMaxMind.com"...)
+	db = append(db, meta...)
+	if err := os.WriteFile(path, db, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxMindProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	writeMMDB(t, path, net.ParseIP("203.0.113.0"), "Testville")
+
+	cfg, _ := utils.IPGeoProviderDefaults("maxmind")
+	cfg.Path = path
+	cfg.ReloadInterval = time.Nanosecond
+	bad, _ := standIn(t, 100, 500, "")
+	chain, err := utils.NewIPGeoChain([]utils.IPGeoProviderConfig{cfg, providerCfg("ipwhois", bad.URL, 0)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	info, err := chain.Lookup(ctx, "203.0.113.50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.City != "Testville" || info.Region != "Test Region" || info.CountryCode != "ZZ" || info.Latitude != 12.5 || info.Longitude != -45.25 || info.Source != "maxmind" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+
+	// 库中没有的地址落到后备提供商
+	if _, err := chain.Lookup(ctx, "198.51.100.1"); err == nil {
+		t.Fatalf("expected fallback failure for unknown ip")
+	}
+
+	// 文件更新后自动重新加载
+	writeMMDB(t, path, net.ParseIP("203.0.113.0"), "Newtown")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+	if info, err := chain.Lookup(ctx, "203.0.113.50"); err != nil || info.City != "Newtown" {
+		t.Fatalf("database not reloaded: %+v %v", info, err)
+	}
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// standIn 本地替身服务：前 failures 次返回 status，之后返回 body
+func standIn(t *testing.T, failures int, status int, body string) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(hits.Add(1)) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func providerCfg(name, baseURL string, retries int) utils.IPGeoProviderConfig {
+	cfg, _ := utils.IPGeoProviderDefaults(name)
+	cfg.BaseURL = baseURL
+	cfg.Retries = retries
+	cfg.Backoff = time.Millisecond
+	cfg.Timeout = time.Second
+	return cfg
+}
+
+func TestIPGeoChain(t *testing.T) {
+	ctx := context.Background()
+	const ip = "203.0.113.7"
+
+	// 可重试错误后重试成功
+	ipapi, hits := standIn(t, 1, http.StatusServiceUnavailable, `{"city":"Sydney","country_code":"AU","latitude":-33.87,"longitude":151.21}`)
+	chain, err := utils.NewIPGeoChain([]utils.IPGeoProviderConfig{providerCfg("ipapi", ipapi.URL, 1)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := chain.Lookup(ctx, ip)
+	if err != nil || info.City != "Sydney" || hits.Load() != 2 {
+		t.Fatalf("retry: info=%+v err=%v hits=%d", info, err, hits.Load())
+	}
+
+	// 不可重试错误直接切换到后备
+	bad, badHits := standIn(t, 100, http.StatusForbidden, "")
+	whois, _ := standIn(t, 0, 0, `{"city":"Tokyo","country_code":"JP","latitude":35.68,"longitude":139.69}`)
+	chain, _ = utils.NewIPGeoChain([]utils.IPGeoProviderConfig{
+		providerCfg("ipapi", bad.URL, 3),
+		providerCfg("ipwhois", whois.URL, 0),
+	}, nil)
+	info, err = chain.Lookup(ctx, ip)
+	if err != nil || info.City != "Tokyo" || badHits.Load() != 1 {
+		t.Fatalf("fallback: info=%+v err=%v hits=%d", info, err, badHits.Load())
+	}
+
+	// 全部失败时返回各提供商的错误
+	chain, _ = utils.NewIPGeoChain([]utils.IPGeoProviderConfig{
+		providerCfg("ipapi", bad.URL, 0),
+		providerCfg("ipwhois", bad.URL, 0),
+	}, nil)
+	if _, err := chain.Lookup(ctx, ip); err == nil || !strings.Contains(err.Error(), "ipapi") || !strings.Contains(err.Error(), "ipwhois") {
+		t.Fatalf("expected combined error, got %v", err)
+	}
+
+	// 私有地址不访问任何提供商
+	if info, err := chain.Lookup(ctx, "192.168.1.2"); err != nil || info.City != "Local Network" {
+		t.Fatalf("private ip: %+v %v", info, err)
+	}
+
+	if _, err := utils.NewIPGeoChain([]utils.IPGeoProviderConfig{{Name: "nope"}}, nil); err == nil {
+		t.Fatalf("unknown provider should be rejected")
+	}
+}
+
+// staticProvider 测试用的自定义提供商
+type staticProvider struct{ name string }
+
+func (p staticProvider) Name() string { return p.name }
+
+func (p staticProvider) Lookup(context.Context, string) (*utils.IPInfo, error) {
+	return &utils.IPInfo{City: "Static", CountryCode: "ZZ"}, nil
+}
+
+func TestRegisterIPGeoProvider(t *testing.T) {
+	utils.RegisterIPGeoProvider(utils.IPGeoProviderConfig{Name: "static-test"}, func(cfg utils.IPGeoProviderConfig, _ *http.Client) utils.IPGeoProvider {
+		return staticProvider{name: cfg.Name}
+	})
+	cfg, ok := utils.IPGeoProviderDefaults("static-test")
+	if !ok {
+		t.Fatalf("provider not registered")
+	}
+	chain, err := utils.NewIPGeoChain([]utils.IPGeoProviderConfig{cfg}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := chain.Lookup(context.Background(), "198.51.100.1"); err != nil || info.City != "Static" {
+		t.Fatalf("custom provider: %+v %v", info, err)
+	}
+}
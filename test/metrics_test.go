@@ -0,0 +1,52 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+	"github.com/LtePrince/Personal-Website-backend/internal/metrics"
+)
+
+func TestInstrumentMetrics(t *testing.T) {
+	// 路由名只在本测试中使用，默认 Registry 上其它测试的计数不会混入
+	const route = "/api/MetricsTest"
+	h := handlers.Instrument(route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	for _, method := range []string{http.MethodGet, http.MethodGet, http.MethodPost, "BREW", "X-RANDOM-1"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, route, nil))
+	}
+
+	srv := httptest.NewServer(metrics.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	body := string(b)
+
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="` + route + `",status="200"} 2`,
+		`http_requests_total{method="POST",route="` + route + `",status="201"} 1`,
+		// 非标准方法归入 OTHER，不原样成为标签
+		`http_requests_total{method="OTHER",route="` + route + `",status="200"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="` + route + `",status="200"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("/metrics missing %q", want)
+		}
+	}
+	if strings.Contains(body, `method="BREW"`) || strings.Contains(body, `method="X-RANDOM-1"`) {
+		t.Fatalf("raw request method leaked into metric labels")
+	}
+}
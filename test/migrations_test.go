@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/migrations"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestMigrationRunner 需要可用的 MongoDB，通过 MONGO_TEST_URI 指定，否则跳过
+func TestMigrationRunner(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	// 独立数据库：migrations 集合与博客集合位于同一数据库，不与其它测试共享版本记录
+	cfg := utils.MongoConfig{
+		URI:            uri,
+		Database:       fmt.Sprintf("migrate_%d", time.Now().UnixNano()),
+		Collection:     "blogs",
+		MaxPoolSize:    2,
+		ConnectTimeout: 5 * time.Second,
+		QueryTimeout:   5 * time.Second,
+		MinBackoff:     time.Second,
+		MaxBackoff:     time.Second,
+	}
+	m := utils.NewMongoManager(cfg, nil)
+	defer m.Close(context.Background())
+	ctx := context.Background()
+	coll, err := m.Blogs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coll.Database().Drop(ctx)
+
+	edited := time.Date(2025, 2, 3, 4, 5, 6, 0, time.UTC)
+	_, err = coll.InsertMany(ctx, []interface{}{
+		bson.D{{Key: "ID", Value: 1}, {Key: "Date", Value: "2024-05-01"}},
+		bson.D{{Key: "ID", Value: 2}, {Key: "Date", Value: "2024-06-01"}, {Key: "UpdatedAt", Value: edited}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := migrations.NewRunner(coll)
+	applied := func() map[int]bool {
+		t.Helper()
+		st, err := r.Status(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make(map[int]bool, len(st))
+		for _, s := range st {
+			out[s.Version] = s.Applied
+		}
+		return out
+	}
+	load := func(id int) bson.M {
+		t.Helper()
+		var doc bson.M
+		if err := coll.FindOne(ctx, bson.D{{Key: "ID", Value: id}}).Decode(&doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	// Up 到指定版本只执行到该版本，版本记录随之写入
+	if n, err := r.Up(ctx, 1); err != nil || n != 1 {
+		t.Fatalf("Up(1) = %d, %v", n, err)
+	}
+	if st := applied(); !st[1] || st[2] {
+		t.Fatalf("status after Up(1) = %v", st)
+	}
+	if n, err := r.Up(ctx, 0); err != nil || n != 1 {
+		t.Fatalf("Up(all) = %d, %v", n, err)
+	}
+	// 已应用的迁移不会重复执行
+	if n, err := r.Up(ctx, 0); err != nil || n != 0 {
+		t.Fatalf("repeated Up = %d, %v", n, err)
+	}
+	if st := applied(); !st[1] || !st[2] {
+		t.Fatalf("status after Up(all) = %v", st)
+	}
+	if _, ok := load(1)["UpdatedAt"]; !ok {
+		t.Fatalf("missing UpdatedAt not backfilled: %v", load(1))
+	}
+
+	// Down 一步只回滚最新版本；只删除回填的 UpdatedAt，迁移前已有的保留
+	if n, err := r.Down(ctx, 1); err != nil || n != 1 {
+		t.Fatalf("Down(1) = %d, %v", n, err)
+	}
+	if st := applied(); !st[1] || st[2] {
+		t.Fatalf("status after Down(1) = %v", st)
+	}
+	if doc := load(1); doc["Date"] != "2024-05-01" || doc["UpdatedAt"] != nil || doc["UpdatedAtBackfilled"] != nil {
+		t.Fatalf("backfilled doc not restored: %v", doc)
+	}
+	doc := load(2)
+	if got, ok := doc["UpdatedAt"].(primitive.DateTime); !ok || !got.Time().Equal(edited) {
+		t.Fatalf("pre-existing UpdatedAt lost on Down: %v", doc)
+	}
+
+	if _, err := r.Down(ctx, 0); err == nil {
+		t.Fatalf("Down(0) should be rejected")
+	}
+	if n, err := r.Down(ctx, 5); err != nil || n != 1 {
+		t.Fatalf("Down(5) = %d, %v", n, err)
+	}
+	if st := applied(); st[1] || st[2] {
+		t.Fatalf("status after full Down = %v", st)
+	}
+	if n, _ := coll.Database().Collection(migrations.CollectionName).CountDocuments(ctx, bson.D{}); n != 0 {
+		t.Fatalf("%d migration record(s) left after full Down", n)
+	}
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// unreachableMongo 指向无人监听的端口，建连总是在 ConnectTimeout 后失败
+func unreachableMongo() utils.MongoConfig {
+	return utils.MongoConfig{
+		URI:            "mongodb://127.0.0.1:1/?directConnection=true",
+		Database:       "test",
+		Collection:     "blogs",
+		MaxPoolSize:    1,
+		ConnectTimeout: 300 * time.Millisecond,
+		QueryTimeout:   time.Second,
+		MinBackoff:     200 * time.Millisecond,
+		MaxBackoff:     time.Minute,
+	}
+}
+
+func TestMongoManagerBackoff(t *testing.T) {
+	m := utils.NewMongoManager(unreachableMongo(), nil)
+	defer m.Close(context.Background())
+
+	err := m.Connect(context.Background())
+	if err == nil || errors.Is(err, utils.ErrMongoUnavailable) {
+		t.Fatalf("first attempt should try to connect and fail, got %v", err)
+	}
+	// 退避期内快速失败
+	start := time.Now()
+	if err := m.Connect(context.Background()); !errors.Is(err, utils.ErrMongoUnavailable) {
+		t.Fatalf("expected ErrMongoUnavailable during backoff, got %v", err)
+	}
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Fatalf("backoff rejection took %s", d)
+	}
+
+	// 退避结束后重新尝试，失败后退避翻倍
+	time.Sleep(250 * time.Millisecond)
+	if err := m.Connect(context.Background()); err == nil || errors.Is(err, utils.ErrMongoUnavailable) {
+		t.Fatalf("expected a new attempt after backoff, got %v", err)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if err := m.Connect(context.Background()); !errors.Is(err, utils.ErrMongoUnavailable) {
+		t.Fatalf("backoff should have doubled, got %v", err)
+	}
+}
+
+func TestMongoManagerCallerCancel(t *testing.T) {
+	m := utils.NewMongoManager(unreachableMongo(), nil)
+	defer m.Close(context.Background())
+
+	// 调用方的超时只让自己放弃等待，不会提前结束共享的建连
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := m.Connect(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected caller deadline, got %v", err)
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Fatalf("cancelled caller waited %s for the connect", d)
+	}
+
+	// 并发调用方加入同一次建连，拿到的是真实的连接错误而非调用方的取消
+	err := m.Connect(context.Background())
+	if err == nil || errors.Is(err, utils.ErrMongoUnavailable) || errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the shared connect error, got %v", err)
+	}
+	if err := m.Connect(context.Background()); !errors.Is(err, utils.ErrMongoUnavailable) {
+		t.Fatalf("failed connect should start backoff, got %v", err)
+	}
+}
+
+// TestMongoLatestBlogMixedDates 需要可用的 MongoDB，通过 MONGO_TEST_URI 指定，否则跳过
+func TestMongoLatestBlogMixedDates(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	cfg := utils.MongoConfig{
+		URI:            uri,
+		Database:       "test",
+		Collection:     fmt.Sprintf("blogs_latest_%d", time.Now().UnixNano()),
+		MaxPoolSize:    2,
+		ConnectTimeout: 5 * time.Second,
+		QueryTimeout:   5 * time.Second,
+		MinBackoff:     time.Second,
+		MaxBackoff:     time.Second,
+	}
+	m := utils.NewMongoManager(cfg, nil)
+	defer m.Close(context.Background())
+	ctx := context.Background()
+	coll, err := m.Blogs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coll.Drop(ctx)
+
+	// 已迁移的旧文章与尚未迁移、日期更新的字符串日期文章并存
+	_, err = coll.InsertMany(ctx, []interface{}{
+		bson.D{{Key: "ID", Value: 1}, {Key: "Title", Value: "typed"}, {Key: "Date", Value: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		bson.D{{Key: "ID", Value: 2}, {Key: "Title", Value: "string"}, {Key: "Date", Value: "2025-03-01"}},
+		bson.D{{Key: "ID", Value: 3}, {Key: "Title", Value: "older string"}, {Key: "Date", Value: "2023-12-31"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := m.GetLatestBlog(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.ID != 2 {
+		t.Fatalf("string-dated newer post hidden by typed dates: got %d", latest.ID)
+	}
+
+	// 全部迁移后走按类型排序的查询
+	if _, err := coll.DeleteMany(ctx, bson.D{{Key: "Date", Value: bson.D{{Key: "$type", Value: "string"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if latest, err := m.GetLatestBlog(ctx); err != nil || latest.ID != 1 {
+		t.Fatalf("typed-only latest = %d, %v", latest.ID, err)
+	}
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LtePrince/Personal-Website-backend/api"
+	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+)
+
+func TestStaticHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Image_1730389545752.jpg": "jpeg",
+		"app.3f9a1c2e.js":         "console.log(1)",
+		"app.3f9a1c2e.js.br":      "brotli-bytes",
+		".env":                    "SECRET=1",
+		"sub/.hidden/a.txt":       "a",
+	}
+	for name, body := range files {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := http.StripPrefix("/static/", handlers.NewStaticHandler(dir, "public, max-age=60"))
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/static/Image_1730389545752.jpg", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "public, max-age=60" || rec.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("plain file: %d %v", rec.Code, rec.Header())
+	}
+
+	rec = get("/static/app.3f9a1c2e.js", "gzip, br")
+	if rec.Body.String() != "brotli-bytes" || rec.Header().Get("Content-Encoding") != "br" {
+		t.Fatalf("precompressed variant not served: %q %v", rec.Body.String(), rec.Header())
+	}
+	if rec.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Fatalf("fingerprinted file not immutable: %v", rec.Header())
+	}
+	if rec := get("/static/app.3f9a1c2e.js", "gzip"); rec.Body.String() != "console.log(1)" || rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("original not served without br: %q", rec.Body.String())
+	}
+
+	for _, p := range []string{"/static/", "/static/sub/", "/static/.env", "/static/sub/.hidden/a.txt", "/static/missing.png"} {
+		rec := get(p, "")
+		var body api.Error
+		if rec.Code != http.StatusNotFound || json.Unmarshal(rec.Body.Bytes(), &body) != nil || body.Code != http.StatusNotFound {
+			t.Fatalf("%s: got %d %q", p, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LtePrince/Personal-Website-backend/api"
+	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+)
+
+// jpegWithGPS 生成 800x400 的 JPEG，带方向=6（需顺时针旋转 90°）与一段 GPS 纬度数据
+func jpegWithGPS(t *testing.T, secret string) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var enc bytes.Buffer
+	if err := jpeg.Encode(&enc, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	le := binary.LittleEndian
+	tiff := []byte("II*\x00")
+	tiff = le.AppendUint32(tiff, 8)
+	// IFD0：Orientation=6，GPSInfo 指向偏移 38
+	tiff = le.AppendUint16(tiff, 2)
+	tiff = le.AppendUint32(le.AppendUint32(le.AppendUint16(le.AppendUint16(tiff, 0x0112), 3), 1), 6)
+	tiff = le.AppendUint32(le.AppendUint32(le.AppendUint16(le.AppendUint16(tiff, 0x8825), 4), 1), 38)
+	tiff = le.AppendUint32(tiff, 0)
+	// GPS IFD：GPSLatitude，3 个 RATIONAL 存放在偏移 56
+	tiff = le.AppendUint16(tiff, 1)
+	tiff = le.AppendUint32(le.AppendUint32(le.AppendUint16(le.AppendUint16(tiff, 0x0002), 5), 3), 56)
+	tiff = le.AppendUint32(tiff, 0)
+	tiff = append(tiff, []byte(secret)...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := binary.BigEndian.AppendUint16([]byte{0xFF, 0xE1}, uint16(len(payload)+2))
+	app1 = append(app1, payload...)
+	src := enc.Bytes()
+	return append(append(append([]byte{}, src[:2]...), app1...), src[2:]...)
+}
+
+func TestUploadImage(t *testing.T) {
+	dir := t.TempDir()
+	cfg := handlers.UploadConfig{
+		Dir:        dir,
+		AdminToken: "s3cret",
+		MaxBytes:   1 << 20,
+		MaxPixels:  1 << 22,
+		Quality:    80,
+		Variants:   []handlers.UploadVariant{{Name: "thumbnail", Width: 100}, {Name: "medium", Width: 1024}},
+	}
+	up := handlers.NewUploader(cfg)
+	secret := "GPS-SECRET-LATITUDE-DATA" // 24 字节
+	post := func(token string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "photo.png") // 文件名与实际类型不符，应按内容识别
+		fw.Write(data)
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/Upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		up.ServeHTTP(rec, req)
+		return rec
+	}
+
+	photo := jpegWithGPS(t, secret)
+	if rec := post("wrong", photo); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token: got %d", rec.Code)
+	}
+	if rec := post("s3cret", []byte("<html>not an image</html>")); rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("non-image: got %d", rec.Code)
+	}
+	if rec := post("s3cret", bytes.Repeat(photo, 1+(1<<20)/len(photo))); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized: got %d", rec.Code)
+	}
+
+	rec := post("s3cret", photo)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("upload: got %d %s", rec.Code, rec.Body.String())
+	}
+	var resp api.UploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.Original.URL, "/static/img-") || !strings.HasSuffix(resp.Original.URL, ".jpg") {
+		t.Fatalf("unexpected URL %q", resp.Original.URL)
+	}
+	// 方向校正后为 400x800
+	if resp.Original.Width != 400 || resp.Original.Height != 800 {
+		t.Fatalf("orientation not applied: %+v", resp.Original)
+	}
+	if th := resp.Variants["thumbnail"]; th.Width != 100 || th.Height != 200 {
+		t.Fatalf("unexpected thumbnail: %+v", th)
+	}
+	if resp.Variants["medium"] != resp.Original {
+		t.Fatalf("medium should reuse the original: %+v", resp.Variants["medium"])
+	}
+
+	stored, err := os.ReadFile(filepath.Join(dir, strings.TrimPrefix(resp.Original.URL, "/static/")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stored, []byte(secret)) {
+		t.Fatalf("GPS data survived upload")
+	}
+	if !bytes.Contains(stored, []byte("Exif\x00\x00")) {
+		t.Fatalf("non-location EXIF should be kept")
+	}
+
+	// 同一内容重复上传得到相同地址
+	var again api.UploadResponse
+	json.Unmarshal(post("s3cret", photo).Body.Bytes(), &again)
+	if again.Original.URL != resp.Original.URL {
+		t.Fatalf("content hash not stable: %s vs %s", again.Original.URL, resp.Original.URL)
+	}
+}
@@ -0,0 +1,219 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+func TestParseMarkdownMeta(t *testing.T) {
+	loc := time.UTC
+	src := "---\nid: 7\ntitle: \"Hello\"\ndate: 2024-10-31\n---\n# Ignored\n\nFirst paragraph\nline two.\n\nSecond.\n"
+	meta := utils.ParseMarkdownMeta([]byte(src), loc)
+	if meta.ID != 7 || meta.Title != "Hello" || meta.Summary != "First paragraph line two." {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+	if !meta.Date.Equal(time.Date(2024, 10, 31, 0, 0, 0, 0, loc)) {
+		t.Fatalf("unexpected date: %s", meta.Date)
+	}
+
+	meta = utils.ParseMarkdownMeta([]byte("# Title Only\n\nBody"), loc)
+	if meta.ID != 0 || meta.Title != "Title Only" || meta.Summary != "Body" {
+		t.Fatalf("unexpected meta without front matter: %+v", meta)
+	}
+}
+
+func TestContentWatcher(t *testing.T) {
+	dir := t.TempDir()
+	root, err := utils.NewContentRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newFakeStore()
+	ctx := context.Background()
+	store.SaveBlog(ctx, utils.BlogDocument{ID: 3, Title: "Old", Path: "old.md"})
+	write := func(name, body string, mtime time.Time) {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, mtime, mtime)
+	}
+	now := time.Now()
+	write("old.md", "# Old\n\nold body", now)
+
+	w := utils.NewContentWatcher(root, store, store, nil, time.Hour)
+	events := w.Subscribe()
+	w.Poll(ctx) // 基线
+
+	// 新增
+	write("new.md", "# New Post\n\nnew body", now)
+	w.Poll(ctx)
+	ev := <-events
+	if ev.Type != utils.ContentCreated || ev.Path != "new.md" || ev.ID != 4 {
+		t.Fatalf("unexpected create event: %+v", ev)
+	}
+	if d, ok := store.get(4); !ok || d.Title != "New Post" || d.Path != "new.md" || d.Date.IsZero() {
+		t.Fatalf("new blog not stored: %+v", d)
+	}
+
+	// 修改
+	write("old.md", "# Renamed\n\nchanged body", now.Add(time.Minute))
+	w.Poll(ctx)
+	ev = <-events
+	if ev.Type != utils.ContentModified || ev.ID != 3 {
+		t.Fatalf("unexpected modify event: %+v", ev)
+	}
+	if d, _ := store.get(3); d.Title != "Renamed" || d.UpdatedAt == nil {
+		t.Fatalf("modified blog not updated: %+v", d)
+	}
+
+	// 删除：连续两次轮询缺失才删除
+	os.Remove(filepath.Join(dir, "new.md"))
+	w.Poll(ctx)
+	if _, ok := store.get(4); !ok {
+		t.Fatalf("blog deleted after a single poll")
+	}
+	w.Poll(ctx)
+	ev = <-events
+	if ev.Type != utils.ContentDeleted || ev.ID != 4 {
+		t.Fatalf("unexpected delete event: %+v", ev)
+	}
+	if _, ok := store.get(4); ok {
+		t.Fatalf("deleted blog still stored")
+	}
+}
+
+// newWatchedRoot 在临时目录中写入 markdown 文件，建立监视器基线
+func newWatchedRoot(t *testing.T, files map[string]string) (string, *fakeStore, *utils.ContentWatcher) {
+	t.Helper()
+	dir := t.TempDir()
+	root, err := utils.NewContentRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newFakeStore()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := utils.NewContentWatcher(root, store, store, nil, time.Hour)
+	ctx := context.Background()
+	w.Poll(ctx) // 基线
+	return dir, store, w
+}
+
+func TestContentWatcherRootUnavailable(t *testing.T) {
+	dir, store, w := newWatchedRoot(t, nil)
+	ctx := context.Background()
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("---\nid: 1\n---\n# A"), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.md"), []byte("---\nid: 2\n---\n# B"), 0o644)
+	w.Poll(ctx)
+	if _, ok := store.get(2); !ok {
+		t.Fatalf("blogs not created")
+	}
+
+	// 根目录整体消失（卷卸载、目录替换）：扫描失败，跳过轮询，不删除任何博客
+	moved := dir + ".moved"
+	if err := os.Rename(dir, moved); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(moved) })
+	w.Poll(ctx)
+	w.Poll(ctx)
+	w.Poll(ctx)
+	if _, ok1 := store.get(1); !ok1 {
+		t.Fatalf("blog deleted while content root was unavailable")
+	}
+
+	// 目录恢复后不产生任何删除
+	if err := os.Rename(moved, dir); err != nil {
+		t.Fatal(err)
+	}
+	w.Poll(ctx)
+	w.Poll(ctx)
+	if _, ok := store.get(2); !ok {
+		t.Fatalf("blog deleted after content root came back")
+	}
+}
+
+func TestContentWatcherTransientMissing(t *testing.T) {
+	dir, store, w := newWatchedRoot(t, nil)
+	ctx := context.Background()
+	p := filepath.Join(dir, "a.md")
+	body := []byte("---\nid: 1\n---\n# A")
+	os.WriteFile(p, body, 0o644)
+	w.Poll(ctx)
+
+	// 只在一次轮询中缺失：不删除
+	st, _ := os.Stat(p)
+	os.Remove(p)
+	w.Poll(ctx)
+	os.WriteFile(p, body, 0o644)
+	os.Chtimes(p, st.ModTime(), st.ModTime())
+	w.Poll(ctx)
+	w.Poll(ctx)
+	if _, ok := store.get(1); !ok {
+		t.Fatalf("blog deleted after a transient disappearance")
+	}
+}
+
+func TestContentWatcherIDConflict(t *testing.T) {
+	dir, store, w := newWatchedRoot(t, nil)
+	ctx := context.Background()
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("---\nid: 5\ntitle: A\n---\nbody"), 0o644)
+	w.Poll(ctx)
+
+	// 另一个文件声明同一个 ID：拒绝，不覆盖已有博客
+	os.WriteFile(filepath.Join(dir, "b.md"), []byte("---\nid: 5\ntitle: B\n---\nbody"), 0o644)
+	w.Poll(ctx)
+	if d, _ := store.get(5); d.Title != "A" || d.Path != "a.md" {
+		t.Fatalf("ID collision overwrote existing blog: %+v", d)
+	}
+	if paths, _ := store.ListBlogPaths(ctx); len(paths) != 1 {
+		t.Fatalf("unexpected blogs: %+v", paths)
+	}
+}
+
+func TestContentWatcherIDChange(t *testing.T) {
+	dir, store, w := newWatchedRoot(t, nil)
+	ctx := context.Background()
+	p := filepath.Join(dir, "a.md")
+	os.WriteFile(p, []byte("---\nid: 5\ntitle: A\n---\nbody"), 0o644)
+	w.Poll(ctx)
+	if _, ok := store.get(5); !ok {
+		t.Fatalf("blog not created")
+	}
+
+	// 同一文件改了 front matter 中的 ID：旧 ID 的博客删除，只留下新 ID
+	os.WriteFile(p, []byte("---\nid: 8\ntitle: A2\n---\nbody"), 0o644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(p, later, later)
+	w.Poll(ctx)
+	if _, ok := store.get(5); ok {
+		t.Fatalf("old blog ID still present after ID change")
+	}
+	if d, ok := store.get(8); !ok || d.Title != "A2" || d.Path != "a.md" {
+		t.Fatalf("renumbered blog not stored: %+v", d)
+	}
+	if paths, _ := store.ListBlogPaths(ctx); len(paths) != 1 {
+		t.Fatalf("unexpected blogs: %+v", paths)
+	}
+}
+
+func TestContentWatcherFencedSummary(t *testing.T) {
+	dir, store, w := newWatchedRoot(t, nil)
+	ctx := context.Background()
+	body := "---\nid: 1\n---\n```sh\n# install\nmake install\n\nmake test\n```\n\nReal summary.\n"
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte(body), 0o644)
+	w.Poll(ctx)
+	// 代码块内的 # 注释不是标题，代码行也不是摘要
+	if d, _ := store.get(1); d.Summary != "Real summary." || d.Title != "a" {
+		t.Fatalf("fenced code leaked into metadata: %+v", d)
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+func TestWeatherCache(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	fetch := func(_ context.Context, lat, lon float64) (*utils.WeatherAQI, error) {
+		calls.Add(1)
+		if failing.Load() {
+			return nil, errors.New("open-meteo down")
+		}
+		temp := lat + lon
+		return &utils.WeatherAQI{TempC: &temp, WeatherText: "晴", FetchedAt: time.Now()}, nil
+	}
+	cache := utils.NewWeatherCache(utils.WeatherCacheConfig{Grid: 0.1, TTL: 40 * time.Millisecond, MaxStale: 100 * time.Millisecond, MaxEntries: 10}, fetch)
+	ctx := context.Background()
+
+	first, err := cache.Current(ctx, 31.2304, 121.4737)
+	if err != nil || calls.Load() != 1 {
+		t.Fatalf("first fetch: %v calls=%d", err, calls.Load())
+	}
+	// 网格中心坐标：31.2, 121.5
+	if *first.TempC < 152.69 || *first.TempC > 152.71 {
+		t.Fatalf("fetch not called with snapped coordinates: %v", *first.TempC)
+	}
+	// 同一网格内的邻近坐标命中缓存
+	if _, err := cache.Current(ctx, 31.24, 121.46); err != nil || calls.Load() != 1 {
+		t.Fatalf("same cell should hit cache: %v calls=%d", err, calls.Load())
+	}
+
+	// 过期后先返回旧数据，后台刷新
+	time.Sleep(50 * time.Millisecond)
+	stale, err := cache.Current(ctx, 31.2304, 121.4737)
+	if err != nil || !stale.FetchedAt.Equal(first.FetchedAt) {
+		t.Fatalf("expected stale data: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	fresh, _ := cache.Current(ctx, 31.2304, 121.4737)
+	if calls.Load() != 2 || !fresh.FetchedAt.After(first.FetchedAt) {
+		t.Fatalf("background refresh did not happen: calls=%d", calls.Load())
+	}
+
+	// 上游故障期间继续使用旧数据，直到超过最大陈旧时间
+	failing.Store(true)
+	time.Sleep(50 * time.Millisecond)
+	if _, err := cache.Current(ctx, 31.2304, 121.4737); err != nil {
+		t.Fatalf("stale data should survive outage: %v", err)
+	}
+	time.Sleep(120 * time.Millisecond)
+	if _, err := cache.Current(ctx, 31.2304, 121.4737); err == nil {
+		t.Fatalf("data beyond max staleness should not be served")
+	}
+}
+
+func TestWeatherCacheDegraded(t *testing.T) {
+	var calls atomic.Int32
+	var aqiDown atomic.Bool
+	fetch := func(_ context.Context, lat, lon float64) (*utils.WeatherAQI, error) {
+		n := float64(calls.Add(1))
+		w := &utils.WeatherAQI{TempC: &n, FetchedAt: time.Now()}
+		if aqiDown.Load() {
+			w.Errors = []*utils.SourceError{{Source: utils.SourceAirQuality, Err: errors.New("down")}}
+		} else {
+			aqi := 42.0
+			w.AQIUS = &aqi
+		}
+		return w, nil
+	}
+	cache := utils.NewWeatherCache(utils.WeatherCacheConfig{
+		Grid: 0.1, TTL: 40 * time.Millisecond, MaxStale: 150 * time.Millisecond, MaxEntries: 10, DegradedTTL: 30 * time.Millisecond,
+	}, fetch)
+	ctx := context.Background()
+	get := func() *utils.WeatherAQI {
+		w, err := cache.Current(ctx, 31.2, 121.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+
+	get()
+	aqiDown.Store(true)
+	time.Sleep(50 * time.Millisecond)
+	get() // 过期：返回旧数据并在后台刷新
+	time.Sleep(20 * time.Millisecond)
+
+	// 刷新得到部分数据：天气取自新数据，空气质量沿用上一份完整数据
+	w := get()
+	if calls.Load() != 2 || *w.TempC != 2 || w.AQIUS == nil || *w.AQIUS != 42 || w.Degraded() {
+		t.Fatalf("degraded refresh not merged: calls=%d %+v", calls.Load(), w)
+	}
+	// DegradedTTL 内不再请求上游
+	for i := 0; i < 10; i++ {
+		get()
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("degraded entry refetched within DegradedTTL: calls=%d", calls.Load())
+	}
+
+	// 上一份完整数据超过最大陈旧时间后不再沿用
+	deadline := time.Now().Add(time.Second)
+	for {
+		w = get()
+		if w.AQIUS == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stale AQI served beyond max staleness")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := w.FailedSources(); len(got) != 1 || got[0] != utils.SourceAirQuality {
+		t.Fatalf("failed sources = %v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+const richCurrentBody = `{"current":{"temperature_2m":3.2,"apparent_temperature":-1.4,"relative_humidity_2m":81,
+"wind_speed_10m":18.5,"wind_direction_10m":227,"wind_gusts_10m":40.2,"weather_code":80,"is_day":0,
+"uv_index":0.04,"pressure_msl":1013.6,"precipitation":1.2,"cloud_cover":88}}`
+
+func TestCompassPoint(t *testing.T) {
+	cases := map[float64]string{0: "N", 11.2: "N", 11.25: "NNE", 45: "NE", 227: "SW", 348.7: "NNW", 359: "N", 360: "N", -90: "W"}
+	for deg, want := range cases {
+		if got := utils.CompassPoint(deg); got != want {
+			t.Fatalf("CompassPoint(%v) = %s, want %s", deg, got, want)
+		}
+	}
+	if utils.CompassText("SW", utils.LangZH) != "西南" || utils.CompassText("SW", utils.LangEN) != "SW" {
+		t.Fatalf("unexpected compass text")
+	}
+}
+
+func TestWeatherIcon(t *testing.T) {
+	day, night := true, false
+	code := func(c int) *int { return &c }
+	cases := []struct {
+		code  *int
+		isDay *bool
+		want  string
+	}{
+		{code(0), &day, "clear-day"},
+		{code(0), &night, "clear-night"},
+		{code(2), nil, "partly-cloudy-day"},
+		{code(3), &night, "overcast"},
+		{code(81), &night, "rain-showers-night"},
+		{code(99), &day, "thunderstorm-hail"},
+		{code(42), &day, "unknown"},
+		{nil, &day, "unknown"},
+	}
+	for _, c := range cases {
+		if got := utils.WeatherIcon(c.code, c.isDay); got != c.want {
+			t.Fatalf("WeatherIcon(%v, %v) = %s, want %s", c.code, c.isDay, got, c.want)
+		}
+	}
+}
+
+func TestWeatherRichConditions(t *testing.T) {
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusOK, richCurrentBody))
+	t.Setenv("OPEN_METEO_AIR_QUALITY_URL", openMeteoStandIn(t, 0, http.StatusOK, `{"current":{"us_aqi":42}}`))
+
+	res, err := utils.FetchWeatherAndAQI(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *res.ApparentTempC != -1.4 || *res.WindDirectionDeg != 227 || *res.WindGustsKmh != 40.2 || *res.UVIndex != 0.04 ||
+		*res.PressureHPa != 1013.6 || *res.PrecipitationMm != 1.2 || *res.CloudCover != 88 || res.IsDay == nil || *res.IsDay {
+		t.Fatalf("unexpected conditions: %+v", res)
+	}
+
+	get := func(query string) map[string]any {
+		// 坐标各不相同，避免命中进程级天气缓存中其他测试的数据
+		req := httptest.NewRequest(http.MethodGet, "/api/Weather?"+query, nil)
+		rec := httptest.NewRecorder()
+		handlers.WeatherHandler(rec, req)
+		var body map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return body
+	}
+
+	body := get("lat=-45&lon=170&lang=zh")
+	want := map[string]any{
+		"apparentTemperature":  float64(-1),
+		"windDirection":        float64(227),
+		"windDirectionCompass": "SW",
+		"windDirectionText":    "西南",
+		"windGust":             float64(40),
+		"uvIndex":              float64(0),
+		"pressure":             float64(1014),
+		"precipitation":        1.2,
+		"cloudCover":           float64(88),
+		"isDay":                false,
+		"icon":                 "rain-showers-night",
+		"weatherText":          "小阵雨",
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Fatalf("metric %s = %v, want %v", k, body[k], v)
+		}
+	}
+
+	body = get("lat=-45&lon=170&units=imperial&lang=en")
+	units, _ := body["units"].(map[string]any)
+	// -1.4°C → 29.48°F，40.2 km/h → 25 mph，1013.6 hPa → 29.93 inHg，1.2 mm → 0.05 in
+	if body["apparentTemperature"] != float64(29) || body["windGust"] != float64(25) || body["pressure"] != 29.93 ||
+		body["precipitation"] != 0.05 || body["windDirectionText"] != "SW" || units["pressure"] != "inHg" || units["precipitation"] != "in" {
+		t.Fatalf("imperial: %v", body)
+	}
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+// openMeteoStandIn 延迟 delay 后返回 body；status 非 200 时返回错误状态
+func openMeteoStandIn(t *testing.T, delay time.Duration, status int, body string) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+const forecastBody = `{"current":{"temperature_2m":21.5,"relative_humidity_2m":60,"wind_speed_10m":12.3,"weather_code":0}}`
+
+func TestFetchWeatherAndAQIConcurrent(t *testing.T) {
+	ctx := context.Background()
+	delay := 150 * time.Millisecond
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, delay, http.StatusOK, forecastBody))
+	t.Setenv("OPEN_METEO_AIR_QUALITY_URL", openMeteoStandIn(t, delay, http.StatusOK, `{"current":{"us_aqi":42}}`))
+
+	start := time.Now()
+	res, err := utils.FetchWeatherAndAQI(ctx, 1, 2)
+	if err != nil || res.Degraded() || *res.TempC != 21.5 || *res.AQIUS != 42 || res.WeatherText != "晴" {
+		t.Fatalf("unexpected result: %+v %v", res, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*delay-20*time.Millisecond {
+		t.Fatalf("requests were not concurrent: %s", elapsed)
+	}
+}
+
+func TestFetchWeatherAndAQIPartial(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusOK, forecastBody))
+	t.Setenv("OPEN_METEO_AIR_QUALITY_URL", openMeteoStandIn(t, 0, http.StatusBadGateway, "upstream down"))
+
+	res, err := utils.FetchWeatherAndAQI(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("partial result expected, got %v", err)
+	}
+	if !res.Degraded() || res.AQIUS != nil || res.TempC == nil {
+		t.Fatalf("unexpected partial result: %+v", res)
+	}
+	if failed := res.FailedSources(); len(failed) != 1 || failed[0] != utils.SourceAirQuality {
+		t.Fatalf("failed sources = %v", failed)
+	}
+
+	// 两个数据源都失败：返回 *WeatherError，逐个列出原因
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusOK, "not json"))
+	_, err = utils.FetchWeatherAndAQI(ctx, 1, 2)
+	var werr *utils.WeatherError
+	if !errors.As(err, &werr) || len(werr.Errors) != 2 {
+		t.Fatalf("expected WeatherError with 2 sources, got %v", err)
+	}
+	var se *utils.SourceError
+	if !errors.As(err, &se) || se.Source != utils.SourceForecast {
+		t.Fatalf("SourceError not reachable through errors.As: %v", err)
+	}
+}
+
+// countingStandIn 与 openMeteoStandIn 相同，另外统计请求次数
+func countingStandIn(t *testing.T, status int, body string) (string, *atomic.Int32) {
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, &n
+}
+
+func TestWeatherCacheSourceOutage(t *testing.T) {
+	forecastURL, forecastCalls := countingStandIn(t, http.StatusOK, forecastBody)
+	aqiURL, aqiCalls := countingStandIn(t, http.StatusServiceUnavailable, "down")
+	t.Setenv("OPEN_METEO_FORECAST_URL", forecastURL)
+	t.Setenv("OPEN_METEO_AIR_QUALITY_URL", aqiURL)
+	const ttl = 300 * time.Millisecond
+	cache := utils.NewWeatherCache(utils.WeatherCacheConfig{Grid: 0.1, TTL: ttl, MaxStale: time.Hour, MaxEntries: 10, DegradedTTL: ttl}, nil)
+
+	// 空气质量持续故障时，一个 TTL 内的并发与后续请求只访问上游一次
+	burst := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w, err := cache.Current(context.Background(), 31.2, 121.5)
+				if err != nil || w.TempC == nil || !w.Degraded() {
+					t.Errorf("unexpected result: %+v, %v", w, err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	burst()
+	burst()
+	if f, a := forecastCalls.Load(), aqiCalls.Load(); f != 1 || a != 1 {
+		t.Fatalf("upstream called forecast=%d aqi=%d times within one TTL", f, a)
+	}
+
+	// 过期后只触发一次后台刷新
+	time.Sleep(ttl + 50*time.Millisecond)
+	burst()
+	time.Sleep(100 * time.Millisecond)
+	burst()
+	if f, a := forecastCalls.Load(), aqiCalls.Load(); f != 2 || a != 2 {
+		t.Fatalf("upstream called forecast=%d aqi=%d times over two TTLs", f, a)
+	}
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LtePrince/Personal-Website-backend/internal/handlers"
+	"github.com/LtePrince/Personal-Website-backend/internal/utils"
+)
+
+func TestWeatherDescription(t *testing.T) {
+	// 每个 WMO 代码在两种语言下都应有专门描述
+	codes := []int{0, 1, 2, 3, 45, 48, 51, 53, 55, 56, 57, 61, 63, 65, 66, 67, 71, 73, 75, 77, 80, 81, 82, 85, 86, 95, 96, 99}
+	seen := map[string]int{}
+	for _, c := range codes {
+		code := c
+		zh, en := utils.WeatherDescription(&code, utils.LangZH), utils.WeatherDescription(&code, utils.LangEN)
+		if zh == "天气" || en == "Weather" {
+			t.Fatalf("code %d has no description: %q / %q", c, zh, en)
+		}
+		if prev, dup := seen[en]; dup {
+			t.Fatalf("codes %d and %d share description %q", prev, c, en)
+		}
+		seen[en] = c
+	}
+	unknown := 42
+	if got := utils.WeatherDescription(&unknown, utils.LangEN); got != "Weather" {
+		t.Fatalf("unknown code = %q", got)
+	}
+	if got := utils.WeatherDescription(nil, utils.LangZH); got != "天气" {
+		t.Fatalf("missing code = %q", got)
+	}
+
+	for tag, want := range map[string]string{"zh-CN": "zh", "EN-us": "en", "en": "en"} {
+		if got, ok := utils.ParseLang(tag); !ok || got != want {
+			t.Fatalf("ParseLang(%q) = %q, %v", tag, got, ok)
+		}
+	}
+	if _, ok := utils.ParseLang("fr"); ok {
+		t.Fatalf("fr should be unsupported")
+	}
+}
+
+func TestUnitSystem(t *testing.T) {
+	u, ok := utils.ParseUnitSystem("Imperial")
+	if !ok || u != utils.UnitsImperial {
+		t.Fatalf("ParseUnitSystem = %q, %v", u, ok)
+	}
+	if got := u.Temperature(100); got != 212 {
+		t.Fatalf("100°C = %v°F", got)
+	}
+	if got := u.Speed(1.609344); got != 1 {
+		t.Fatalf("1.609344 km/h = %v mph", got)
+	}
+	if utils.UnitsMetric.Temperature(21.5) != 21.5 || utils.UnitsMetric.SpeedLabel() != "km/h" || u.TemperatureLabel() != "°F" {
+		t.Fatalf("unexpected metric conversion or labels")
+	}
+	if _, ok := utils.ParseUnitSystem("kelvin"); ok {
+		t.Fatalf("kelvin should be unsupported")
+	}
+}
+
+func TestWeatherLangAndUnits(t *testing.T) {
+	t.Setenv("OPEN_METEO_FORECAST_URL", openMeteoStandIn(t, 0, http.StatusOK, forecastBody))
+	t.Setenv("OPEN_METEO_AIR_QUALITY_URL", openMeteoStandIn(t, 0, http.StatusOK, `{"current":{"us_aqi":42}}`))
+
+	get := func(query, acceptLanguage string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodGet, "/api/Weather?lat=12.3&lon=45.6&"+query, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		rec := httptest.NewRecorder()
+		handlers.WeatherHandler(rec, req)
+		var body map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec, body
+	}
+
+	cases := []struct {
+		query, acceptLanguage, lang, text string
+	}{
+		{"", "", "zh", "晴"},
+		{"lang=en", "zh-CN", "en", "Clear sky"},
+		{"", "en-US,en;q=0.9,zh;q=0.8", "en", "Clear sky"},
+		{"", "fr-FR, zh;q=0.4, en;q=0.6", "en", "Clear sky"},
+		{"", "de", "zh", "晴"},
+		// q 值排序与主标签匹配
+		{"", "zh;q=0.5, en-GB;q=0.8", "en", "Clear sky"},
+		{"", "EN-us;q=0.3, fr;q=1, zh-Hant-TW;q=0.7", "zh", "晴"},
+		{"", "en;q=0.9, zh;q=0.90", "en", "Clear sky"}, // 同 q 值取靠前的
+		{"", "en;q=0, zh-TW", "zh", "晴"},
+		{"", "zh;q=0", "en", "Clear sky"},   // 中文被明确拒绝
+		{"", "en;q=2, zh;q=0.1", "zh", "晴"}, // 非法 q 值的条目忽略
+		{"", "en;q=abc, zh;q=0.1", "zh", "晴"},
+		{"", "*;q=0.9, en;q=0.5", "en", "Clear sky"},
+	}
+	for _, c := range cases {
+		rec, body := get(c.query, c.acceptLanguage)
+		if rec.Code != http.StatusOK || body["lang"] != c.lang || body["weatherText"] != c.text {
+			t.Fatalf("%q / %q: %d lang=%v text=%v", c.query, c.acceptLanguage, rec.Code, body["lang"], body["weatherText"])
+		}
+		if rec.Header().Get("Vary") != "Accept-Language" {
+			t.Fatalf("Vary = %q", rec.Header().Get("Vary"))
+		}
+	}
+
+	_, body := get("units=imperial", "")
+	units, _ := body["units"].(map[string]any)
+	// 21.5°C → 70.7°F，12.3 km/h → 7.6 mph；公制字段保持不变，同样四舍五入
+	if body["temperature"] != float64(71) || body["windSpeed"] != float64(8) || body["temperatureC"] != float64(22) ||
+		units["system"] != "imperial" || units["temperature"] != "°F" || units["windSpeed"] != "mph" {
+		t.Fatalf("imperial: %v", body)
+	}
+	_, body = get("", "")
+	if units, _ := body["units"].(map[string]any); units["temperature"] != "°C" || body["temperature"] != float64(22) ||
+		body["temperatureC"] != body["temperature"] || body["windSpeedKmh"] != body["windSpeed"] {
+		t.Fatalf("metric default: %v", body)
+	}
+
+	for _, q := range []string{"units=kelvin", "lang=fr"} {
+		if rec, _ := get(q, ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d, want 400", q, rec.Code)
+		}
+	}
+}
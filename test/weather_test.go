@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/LtePrince/Personal-Website-backend/internal/utils"
@@ -27,7 +28,7 @@ func TestWeatherCodeToText(t *testing.T) {
 }
 
 func TestFetchWeatherAndAQI(t *testing.T) {
-	res, err := utils.FetchWeatherAndAQI(-33.8688, 151.2093) // Sydney 经纬度
+	res, err := utils.FetchWeatherAndAQI(context.Background(), -33.8688, 151.2093) // Sydney 经纬度
 	if err != nil {
 		// 网络失败不视为致命，记录并返回（保持流水线鲁棒）
 		t.Logf("FetchWeatherAndAQI error: %v (tolerated)", err)
@@ -43,7 +44,7 @@ func TestFetchWeatherAndAQI(t *testing.T) {
 
 func TestLookupIPLocation(t *testing.T) {
 	ip := "154.37.213.201"
-	info, err := utils.LookupIPLocation(ip)
+	info, err := utils.LookupIPLocation(context.Background(), ip)
 	if err != nil {
 		t.Logf("LookupIPLocation network error (tolerated): %v", err)
 		return